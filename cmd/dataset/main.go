@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/services/dataset"
+	"MicroserviceWebsocket/internal/storage/postgresql"
+)
+
+func main() {
+	var (
+		databaseURL string
+		modelID     int64
+		from, to    string
+		rating      string
+		contextN    int
+		redact      bool
+		out         string
+	)
+
+	flag.StringVar(&databaseURL, "database-url", "", "PostgreSQL connection URL")
+	flag.Int64Var(&modelID, "model-id", 0, "only this bot_models.id (0 = all)")
+	flag.StringVar(&from, "from", "", "rated at or after, YYYY-MM-DD or RFC3339")
+	flag.StringVar(&to, "to", "", "rated before, YYYY-MM-DD (inclusive day) or RFC3339")
+	flag.StringVar(&rating, "rating", "", "positive | negative (default all)")
+	flag.IntVar(&contextN, "context", 6, "previous chat messages per record")
+	flag.BoolVar(&redact, "redact", false, "replace emails, phones, cards and IPs with placeholders")
+	flag.StringVar(&out, "out", "", "output file (default dataset-<time>.jsonl, - for stdout)")
+	flag.Parse()
+
+	if databaseURL == "" {
+		panic("database-url is required")
+	}
+	if rating != "" && rating != "positive" && rating != "negative" {
+		panic("rating must be positive or negative")
+	}
+	if contextN < 0 {
+		panic("context must be >= 0")
+	}
+
+	f := models.DatasetFilter{Rating: rating, ContextTurns: contextN, Redact: redact}
+	if modelID > 0 {
+		f.ModelID = &modelID
+	}
+	if from != "" {
+		f.From = mustParseTime(from, false)
+	}
+	if to != "" {
+		f.To = mustParseTime(to, true)
+	}
+
+	log := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	storage, err := postgresql.New(databaseURL, log)
+	if err != nil {
+		panic(err)
+	}
+
+	w := os.Stdout
+	if out != "-" {
+		if out == "" {
+			out = dataset.FileName(time.Now())
+		}
+		w, err = os.Create(out)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	n, err := dataset.WriteJSONL(context.Background(), w, storage, f)
+	if err != nil {
+		panic(err)
+	}
+	if w != os.Stdout {
+		if err := w.Close(); err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(os.Stderr, "exported %d records to %s\n", n, out)
+	}
+}
+
+// mustParseTime: YYYY-MM-DD или RFC3339; для верхней границы дата без времени — весь день включительно
+func mustParseTime(s string, upper bool) time.Time {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic("invalid time " + s + ": use YYYY-MM-DD or RFC3339")
+	}
+	return t
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"MicroserviceWebsocket/internal/config"
+	"MicroserviceWebsocket/internal/services/purge"
+	"MicroserviceWebsocket/internal/storage/postgresql"
+)
+
+func main() {
+	var (
+		databaseURL string
+		retention   time.Duration
+		batchSize   int
+		dryRun      bool
+	)
+
+	flag.StringVar(&databaseURL, "database-url", "", "PostgreSQL connection URL")
+	flag.DurationVar(&retention, "retention", 30*24*time.Hour, "purge rows deleted earlier than now-retention")
+	flag.IntVar(&batchSize, "batch-size", 500, "rows per transaction")
+	flag.BoolVar(&dryRun, "dry-run", false, "only report what would be deleted")
+	flag.Parse()
+
+	if databaseURL == "" {
+		panic("database-url is required")
+	}
+	if retention <= 0 || batchSize <= 0 {
+		panic("retention and batch-size must be positive")
+	}
+
+	log := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	storage, err := postgresql.New(databaseURL, log)
+	if err != nil {
+		panic(err)
+	}
+
+	purger := purge.New(log, storage, config.Purge{
+		Retention: retention,
+		BatchSize: batchSize,
+		DryRun:    dryRun,
+	})
+
+	report, err := purger.RunOnce(context.Background())
+	if err != nil {
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+}
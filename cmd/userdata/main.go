@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slog"
+
+	"MicroserviceWebsocket/internal/services/userdata"
+	"MicroserviceWebsocket/internal/storage/postgresql"
+)
+
+func main() {
+	var (
+		databaseURL string
+		userID      int64
+		mode        string
+		out         string
+		confirm     bool
+	)
+
+	flag.StringVar(&databaseURL, "database-url", "", "PostgreSQL connection URL")
+	flag.Int64Var(&userID, "user-id", 0, "user id")
+	flag.StringVar(&mode, "mode", "export", "export | delete | anonymize")
+	flag.StringVar(&out, "out", "", "archive path for export (default user-<id>-data.zip)")
+	flag.BoolVar(&confirm, "yes", false, "confirm irreversible delete/anonymize")
+	flag.Parse()
+
+	if databaseURL == "" {
+		panic("database-url is required")
+	}
+	if userID <= 0 {
+		panic("user-id is required")
+	}
+
+	log := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	storage, err := postgresql.New(databaseURL, log)
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.Background()
+
+	switch mode {
+	case "export":
+		data, err := storage.UserData(ctx, userID)
+		if err != nil {
+			panic(err)
+		}
+		if out == "" {
+			out = userdata.FileName(userID)
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			panic(err)
+		}
+		if err := userdata.WriteArchive(f, data); err != nil {
+			_ = f.Close()
+			panic(err)
+		}
+		if err := f.Close(); err != nil {
+			panic(err)
+		}
+		fmt.Println("exported to", out)
+
+	case "delete", "anonymize":
+		if !confirm {
+			panic("refusing to " + mode + " user data without -yes")
+		}
+		report, err := storage.EraseUser(ctx, userID, mode == "anonymize")
+		if err != nil {
+			panic(err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+
+	default:
+		panic("mode must be one of: export, delete, anonymize")
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"MicroserviceWebsocket/internal/server/handlers"
 	"MicroserviceWebsocket/internal/server/http"
 	"MicroserviceWebsocket/internal/services/neural"
+	"MicroserviceWebsocket/internal/services/purge"
 	"MicroserviceWebsocket/internal/storage/postgresql"
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -36,18 +38,30 @@ func main() {
 	}
 
 	//инициализация подключения к беку
-	neuralClient := neural.NewClient(cfg.NEURALCLIENT.URLNeural, cfg.NEURALCLIENT.Timeout)
+	// отдельные gateway моделей (bot_models.endpoint) подключаются по первому запросу
+	neuralClient := neural.NewPool(cfg.NEURALCLIENT.URLNeural, cfg.NEURALCLIENT.Timeout)
+	defer neuralClient.Close()
 	log.Info("Neural service activate")
 
 	//создание бд, да плохо
-	httpApi := http.NewAPI(log, storage)
+	// hub: открытые ws-сессии, через него HTTP API рассылает события
+	hub := handlers.NewHub()
+	httpApi := http.NewAPI(log, storage, hub, cfg)
 	// wsHandler := handlers.NewWebSocketHandler(*authClient, neuralClient)
-	wsHandler := handlers.NewWebSocketHandler(neuralClient, storage)
+	wsHandler := handlers.NewWebSocketHandler(neuralClient, storage, cfg.WEBSOCKET, hub)
 	//здесь создание создание http.Api handler
 	app := ws.New(log, cfg, wsHandler, httpApi)
 
 	go app.MustRun()
 
+	// фоновая очистка корзины
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	if cfg.PURGE.Enabled {
+		go purge.New(log, storage, cfg.PURGE).Run(purgeCtx)
+		log.Info("purge job started", slog.Duration("interval", cfg.PURGE.Interval))
+	}
+
 	// TODO: сделать корректную обработку сигналов для остановки grpc serv
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT) // приходят сигналы и запысиваются в канал stop
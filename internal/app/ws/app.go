@@ -48,10 +48,23 @@ func New(
 ) *App {
 	// Создаем HTTP сервер с WebSocket хендлером
 	mux := http.NewServeMux()
+	// /ws?user_id={id} — без user_id апгрейд отклоняется с 400 (квоты и роли считаются по пользователю)
 	mux.HandleFunc("/ws", wsHandler.HandleConnection)
 	mux.HandleFunc("/chats", httpAPI.Chats)     // POST /chats, GET /chats?user_id=...
-	mux.HandleFunc("/chats/", httpAPI.ChatByID) // GET /chats/{id}/messages, DELETE /chats/{id}
+	mux.HandleFunc("/chats/", httpAPI.ChatByID) // GET /chats/{id}/messages, DELETE|PATCH /chats/{id}, POST /chats/import
 	mux.HandleFunc("/messages/", httpAPI.MessageByID)
+	mux.HandleFunc("/models", httpAPI.Models)        // GET активные модели для выбора
+	mux.HandleFunc("/templates", httpAPI.Templates)  // GET|POST /templates
+	mux.HandleFunc("/templates/", httpAPI.Templates) // GET|PATCH|DELETE /templates/{id}
+	mux.HandleFunc("/folders", httpAPI.Folders)      // GET|POST /folders
+	mux.HandleFunc("/folders/", httpAPI.Folders)     // PATCH|DELETE /folders/{id}
+	mux.HandleFunc("/tags", httpAPI.Tags)            // GET|POST /tags
+	mux.HandleFunc("/tags/", httpAPI.Tags)           // PATCH|DELETE /tags/{id}
+	mux.HandleFunc("/usage", httpAPI.Usage)          // GET /usage?user_id=...
+	mux.HandleFunc("/search", httpAPI.Search)        // GET /search?user_id=...&q=...
+	mux.HandleFunc("/users/", httpAPI.UserByID)      // GET /users/{id}/export?user_id={id}
+	mux.HandleFunc("/shared/", httpAPI.Shared)       // GET /shared/{token}, без авторизации
+	mux.HandleFunc("/admin/", httpAPI.Admin)         // admin token required
 	mux.HandleFunc("/health", healthHandler)
 
 	server := &http.Server{
@@ -15,6 +15,9 @@ type Config struct {
 	AUTH         AuthGRPCConfig `yaml:"auth"`
 	WEBSOCKET    WebSocket      `yaml:"websocket"`
 	NEURALCLIENT NeuralClient   `yaml:"neuralclient"`
+	PURGE        Purge          `yaml:"purge"`
+	FEEDBACK     Feedback       `yaml:"feedback"`
+	ADMIN        Admin          `yaml:"admin"`
 }
 
 type AuthGRPCConfig struct {
@@ -34,14 +37,46 @@ type BatcherConfig struct {
 type WebSocket struct {
 	URLWS   string        `yaml:"urlws"`
 	Timeout time.Duration `yaml:"timeout"`
+	// ограничения на входящие сообщения клиента. Фрейм больше max_frame_size
+	// закрывает соединение с кодом 1009 без validation_error — это контракт;
+	// max_frame_size держим с запасом над max_prompt_length, чтобы длинный
+	// промпт получал validation_error по полю, а не обрыв
+	MaxFrameSize    int64 `yaml:"max_frame_size" env-default:"65536"`   // байт на один ws-фрейм
+	MaxPromptLength int   `yaml:"max_prompt_length" env-default:"8000"` // символов в message
 }
 
+// запас фрейма на символ промпта: до 6 байт (\uXXXX в JSON) плюс конверт сообщения
+const (
+	frameBytesPerChar = 6
+	frameEnvelope     = 4096
+)
+
 // структура для соединения с беком нейронки
 type NeuralClient struct {
 	URLNeural string        `yaml:"URLNeural"`
 	Timeout   time.Duration `yaml:"timeout"`
 }
 
+// доступ к /admin/* по заголовку Authorization: Bearer <token>
+type Admin struct {
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+}
+
+// допустимые коды причин в feedback и лимит комментария
+type Feedback struct {
+	Reasons          []string `yaml:"reasons" env-default:"incorrect,unsafe,unhelpful,offensive,other"`
+	MaxCommentLength int      `yaml:"max_comment_length" env-default:"2000"`
+}
+
+// фоновая очистка soft-deleted строк
+type Purge struct {
+	Enabled   bool          `yaml:"enabled"`
+	Interval  time.Duration `yaml:"interval" env-default:"1h"`
+	Retention time.Duration `yaml:"retention" env-default:"720h"` // сколько хранить удалённое
+	BatchSize int           `yaml:"batch_size" env-default:"500"`
+	DryRun    bool          `yaml:"dry_run"` // только посчитать и залогировать
+}
+
 // парсит и возвращает объект конфига
 func MustLoadByPath(configPath string) *Config {
 
@@ -55,6 +90,15 @@ func MustLoadByPath(configPath string) *Config {
 		panic("failed tp read config: " + err.Error())
 	}
 
+	if ws := cfg.WEBSOCKET; ws.MaxFrameSize > 0 && ws.MaxFrameSize < int64(ws.MaxPromptLength)*frameBytesPerChar+frameEnvelope {
+		panic("websocket: max_frame_size must be at least 6*max_prompt_length+4096 bytes")
+	}
+
+	// interval <= 0 роняет time.NewTicker, batch_size = 0 зацикливает PurgeDeleted
+	if p := cfg.PURGE; p.Enabled && (p.Interval <= 0 || p.Retention <= 0 || p.BatchSize <= 0) {
+		panic("purge: interval, retention and batch_size must be positive")
+	}
+
 	return &cfg
 }
 
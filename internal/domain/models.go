@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type BatchItem struct {
 	Request Request
 	Future  chan *Response
@@ -10,12 +12,24 @@ type Request struct {
 	ModelName string `json:"model_name"`
 	Message   string `json:"message"`
 	ChatUUID  string `json:"chat_uuid"`
+	// только для первого сообщения в ещё не созданный чат
+	ModelVersion string `json:"model_version,omitempty"`
+	// вместо message: шаблон из /templates и значения его переменных
+	TemplateID *int64            `json:"template_id,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	// arena: тот же промпт параллельно в несколько моделей (вместо модели чата)
+	ArenaModelIDs []int64 `json:"arena_model_ids,omitempty"`
+	// итоговые параметры генерации для gateway; от клиента не принимаются
+	Settings *GenerationParams `json:"settings,omitempty"`
 }
 
 type Response struct {
 	UUID      string `json:"uuid"`
 	Response  string `json:"response"`
 	CreatedAt string `json:"created_at"`
+	// gateway может прислать точный подсчёт токенов, иначе оцениваем сами
+	PromptTokens   int `json:"prompt_tokens,omitempty"`
+	ResponseTokens int `json:"response_tokens,omitempty"`
 }
 
 type WSPing struct {
@@ -25,6 +39,56 @@ type WSPong struct {
 	Type string `json:"type"` // "pong"
 }
 
+// ошибка, отправляемая клиенту по ws
+type WSError struct {
+	Error  string       `json:"error"`
+	Msg    string       `json:"msg"`
+	Fields []FieldError `json:"fields,omitempty"` // только для validation_error
+}
+
+type FieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"msg"`
+}
+
+type WSChatCreated struct {
+	Type         string `json:"type"` // "chat_created"
+	ChatUUID     string `json:"chat_uuid"`
+	Title        string `json:"title"`
+	ModelID      int64  `json:"model_id"`
+	ModelName    string `json:"model_name"`
+	ModelVersion string `json:"model_version"`
+}
+
+// сессии всех участников чата: сообщения скрыты через HTTP API
+type WSMessagesDeleted struct {
+	Type         string   `json:"type"` // "messages_deleted"
+	ChatUUID     string   `json:"chat_uuid"`
+	MessageUUIDs []string `json:"message_uuids"`
+}
+
+// смена модели чата: {"type":"switch_model","chat_uuid":"...","model_id":2}
+type WSSwitchModel struct {
+	Type     string `json:"type"` // "switch_model"
+	ChatUUID string `json:"chat_uuid"`
+	ModelID  int64  `json:"model_id"`
+}
+
+type WSModelSwitched struct {
+	Type string `json:"type"` // "model_switched"
+	ChatModelResp
+}
+
+// сессии остальных участников: новое сообщение в общем чате
+type WSUserMessage struct {
+	Type        string `json:"type"` // "user_message"
+	ChatUUID    string `json:"chat_uuid"`
+	MessageUUID string `json:"message_uuid"`
+	UserID      int64  `json:"user_id"`
+	Content     string `json:"content"`
+	CreatedAt   string `json:"created_at"`
+}
+
 type WSBotMessage struct {
 	Type            string `json:"type"`
 	ChatUUID        string `json:"chat_uuid"`
@@ -32,6 +96,9 @@ type WSBotMessage struct {
 	BotMessageUUID  string `json:"bot_message_uuid"`
 	Response        string `json:"response"`
 	CreatedAt       string `json:"created_at"`
+	// только в arena: какая модель ответила
+	ModelID   int64  `json:"model_id,omitempty"`
+	ModelName string `json:"model_name,omitempty"`
 }
 
 // -------------------- HTTP models --------------------
@@ -48,39 +115,601 @@ type CreateChatResp struct {
 	ChatUUID string `json:"chat_uuid"`
 }
 
+// ---------- pagination: ?limit=&before=|after= ----------
+type PageReq struct {
+	Limit  int
+	Before string // cursor
+	After  string // cursor
+}
+
+// ---------- POST /chats/import?user_id=123&format=native|chatgpt ----------
+type ImportMessage struct {
+	MessageUUID      string    `json:"message_uuid"`
+	Role             string    `json:"role"` // user|bot
+	Content          string    `json:"content"`
+	CreatedAt        time.Time `json:"created_at"`
+	ReplyToMessageID string    `json:"reply_to_message_id,omitempty"`
+}
+
+type ImportChat struct {
+	ChatUUID     string          `json:"chat_uuid"`
+	Title        string          `json:"title"`
+	ModelName    string          `json:"model_name,omitempty"` // иначе берётся из ImportReq
+	ModelVersion string          `json:"model_version,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Messages     []ImportMessage `json:"messages"`
+}
+
+type ImportReq struct {
+	ModelName    string       `json:"model_name"` // модель по умолчанию для чатов без своей
+	ModelVersion string       `json:"model_version"`
+	Chats        []ImportChat `json:"chats"`
+}
+
+type ImportFailure struct {
+	Index    int    `json:"index"` // позиция чата во входном документе
+	ChatUUID string `json:"chat_uuid,omitempty"`
+	Error    string `json:"error"`
+}
+
+type ImportResp struct {
+	Imported []string        `json:"imported"` // chat_uuid
+	Failed   []ImportFailure `json:"failed"`
+}
+
 // ---------- GET /chats?user_id=123 ----------
 type ChatItem struct {
-	ID        string `json:"id"` // chat_uuid
-	Title     string `json:"title"`
-	ModelID   int64  `json:"model_id"` // bot_models.id (BIGINT)
-	UpdatedAt string `json:"updated_at"`
+	ID            string  `json:"id"` // chat_uuid
+	Title         string  `json:"title"`
+	ModelID       int64   `json:"model_id"` // bot_models.id (BIGINT)
+	UpdatedAt     string  `json:"updated_at"`
+	LastMessageAt string  `json:"last_message_at"`        // порядок списка и курсора
+	LastMessage   string  `json:"last_message,omitempty"` // превью последнего сообщения
+	Pinned        bool    `json:"pinned"`
+	Archived      bool    `json:"archived"`
+	DeletedAt     string  `json:"deleted_at,omitempty"` // только в корзине
+	Role          string  `json:"role"`                 // роль текущего пользователя
+	FolderID      *int64  `json:"folder_id,omitempty"`  // папка текущего пользователя
+	TagIDs        []int64 `json:"tag_ids"`              // теги текущего пользователя
+}
+
+// ?archived=true|false (default false), ?pinned=true|false, ?deleted=true (корзина)
+type ChatsFilter struct {
+	Archived bool
+	Pinned   *bool // nil = все
+	Deleted  bool
+	FolderID *int64
+	TagID    *int64
 }
 
 type ListChatsResp struct {
-	Items []ChatItem `json:"items"`
+	Items      []ChatItem `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// ---------- PATCH /chats/{chat_id} ----------
+// nil-поля не меняются. title — общий (только владелец),
+// pinned/archived — личные флаги участника (любая роль)
+type UpdateChatReq struct {
+	UserID   int64   `json:"user_id"`
+	Title    *string `json:"title"`
+	Pinned   *bool   `json:"pinned"`
+	Archived *bool   `json:"archived"`
 }
 
 // ---------- GET /chats/{chat_id}/messages ----------
 type MessageItem struct {
-	ID               string `json:"id"`   // message_uuid
-	Role             string `json:"role"` // user|bot
+	ID               string `json:"id"`                // message_uuid
+	Role             string `json:"role"`              // user|bot
+	UserID           int64  `json:"user_id,omitempty"` // автор user-сообщения
 	Content          string `json:"content"`
 	CreatedAt        string `json:"created_at"`
 	ReplyToMessageID string `json:"reply_to_message_id,omitempty"`
+	Feedback         *bool  `json:"feedback,omitempty"` // is_positive от текущего пользователя
+	// модель, которая сгенерировала ответ (только у bot-сообщений)
+	ModelID      int64  `json:"model_id,omitempty"`
+	ModelName    string `json:"model_name,omitempty"`
+	ModelVersion string `json:"model_version,omitempty"`
 }
 
 type ListMessagesResp struct {
-	ChatID string        `json:"chat_id"` // chat_uuid
-	Items  []MessageItem `json:"items"`
+	ChatID     string        `json:"chat_id"` // chat_uuid
+	Items      []MessageItem `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// ---------- DELETE /messages/{message_id}?user_id=123 ----------
+type DeleteMessageResp struct {
+	ChatID     string   `json:"chat_id"`
+	DeletedIDs []string `json:"deleted_ids"` // сам message + ответы бота на него
+}
+
+// ---------- GET /search?user_id=123&q=... ----------
+type SearchSnippet struct {
+	MessageID string `json:"message_id"`
+	Role      string `json:"role"`
+	Snippet   string `json:"snippet"` // html-escaped, совпадения в <mark></mark>
+	CreatedAt string `json:"created_at"`
+}
+
+type SearchItem struct {
+	ChatID         string          `json:"chat_id"`
+	Title          string          `json:"title"`
+	TitleHighlight string          `json:"title_highlight,omitempty"` // если совпал заголовок
+	UpdatedAt      string          `json:"updated_at"`
+	Snippets       []SearchSnippet `json:"snippets"`
+}
+
+type SearchResp struct {
+	Query string       `json:"query"`
+	Items []SearchItem `json:"items"`
+}
+
+// ---------- GET /chats/{chat_id}/export?format=md|json|html ----------
+type ChatInfo struct {
+	ID           string `json:"id"` // chat_uuid
+	Title        string `json:"title"`
+	ModelID      int64  `json:"model_id"`
+	ModelName    string `json:"model_name"`
+	ModelVersion string `json:"model_version"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+// ---------- /chats/{chat_id}/members ----------
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor" // может писать в чат
+	RoleViewer = "viewer" // только чтение
+)
+
+type ChatMember struct {
+	UserID    int64  `json:"user_id"`
+	Role      string `json:"role"`
+	InvitedBy int64  `json:"invited_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type AddMemberReq struct {
+	UserID   int64  `json:"user_id"`   // кто приглашает (owner)
+	MemberID int64  `json:"member_id"` // кого
+	Role     string `json:"role"`      // editor|viewer
+}
+
+type ListMembersResp struct {
+	ChatID string       `json:"chat_id"`
+	Items  []ChatMember `json:"items"`
+}
+
+// ---------- /folders, /tags ----------
+// Label — папка или тег: устроены одинаково
+type Label struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Chats     int64  `json:"chats"` // сколько чатов в папке/с тегом
+	CreatedAt string `json:"created_at"`
+}
+
+type LabelReq struct {
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+type ListLabelsResp struct {
+	Items []Label `json:"items"`
+}
+
+// PUT /chats/{chat_id}/folder; folder_id = null — убрать из папки
+type ChatFolderReq struct {
+	UserID   int64  `json:"user_id"`
+	FolderID *int64 `json:"folder_id"`
+}
+
+// GET|PUT /chats/{chat_id}/settings
+// settings — переопределения чата, defaults — из модели, effective — что уйдёт в gateway
+type ChatSettingsResp struct {
+	ChatUUID    string           `json:"chat_uuid"`
+	ModelID     int64            `json:"model_id"`
+	ModelName   string           `json:"model_name"`
+	ContextSize int              `json:"context_size,omitempty"`
+	Settings    GenerationParams `json:"settings"`
+	Endpoint    string           `json:"-"` // gateway модели; "" — общий
+	Defaults    GenerationParams `json:"defaults"`
+	Effective   GenerationParams `json:"effective"`
+}
+
+// PUT /chats/{chat_id}/model — новые ответы в чате генерирует другая модель
+type ChatModelReq struct {
+	UserID  int64 `json:"user_id"`
+	ModelID int64 `json:"model_id"`
+}
+
+type ChatModelResp struct {
+	ChatUUID     string `json:"chat_uuid"`
+	ModelID      int64  `json:"model_id"`
+	ModelName    string `json:"model_name"`
+	ModelVersion string `json:"model_version"`
+}
+
+// PUT заменяет переопределения целиком; пустой settings — сброс к defaults
+type ChatSettingsReq struct {
+	UserID   int64            `json:"user_id"`
+	Settings GenerationParams `json:"settings"`
+}
+
+// POST /chats/{chat_id}/tags
+type ChatTagReq struct {
+	UserID int64 `json:"user_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+// ---------- POST|DELETE /chats/{chat_id}/share, GET /shared/{token} ----------
+type ShareResp struct {
+	Token       string `json:"token"`
+	ChatID      string `json:"chat_id"`
+	SharedUntil string `json:"shared_until"`
+}
+
+type SharedChatResp struct {
+	Title       string        `json:"title"`
+	SharedUntil string        `json:"shared_until"`
+	Items       []MessageItem `json:"items"`
+	NextCursor  string        `json:"next_cursor,omitempty"`
 }
 
 // ---------- POST /messages/{message_id}/feedback ----------
 type FeedbackReq struct {
-	UserID     int64 `json:"user_id"`
-	IsPositive bool  `json:"is_positive"`
+	UserID     int64  `json:"user_id"`
+	IsPositive bool   `json:"is_positive"`
+	Reason     string `json:"reason,omitempty"`  // код из config feedback.reasons
+	Comment    string `json:"comment,omitempty"` // свободный текст
 }
 
 type FeedbackResp struct {
 	MessageID  string `json:"message_id"` // message_uuid
 	IsPositive bool   `json:"is_positive"`
+	Reason     string `json:"reason,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+// POST /messages/{message_id}/preference — выбор лучшего ответа в arena
+type ArenaPreferenceReq struct {
+	UserID int64 `json:"user_id"`
+}
+
+type ArenaPreferenceResp struct {
+	PromptMessageID string  `json:"prompt_message_id"` // user message
+	MessageID       string  `json:"message_id"`        // выбранный ответ
+	ModelID         int64   `json:"model_id"`
+	ComparedWith    []int64 `json:"compared_with"` // model_id остальных ответов
+	UpdatedAt       string  `json:"updated_at"`
+}
+
+// ответ бота целиком: сообщение + метаданные генерации + usage
+type BotTurn struct {
+	ChatUUID    string
+	MessageUUID string
+	ReplyToUUID string // user message
+	Content     string
+	ModelID     int64
+	LatencyMs   int64
+	Usage       UsageRecord
+}
+
+// ---------- GET /models, /admin/models ----------
+// GenerationParams — параметры генерации; nil = не задано (берётся значение уровнем выше)
+type GenerationParams struct {
+	Temperature  *float64 `json:"temperature,omitempty"`
+	TopP         *float64 `json:"top_p,omitempty"`
+	MaxTokens    *int     `json:"max_tokens,omitempty"`
+	SystemPrompt *string  `json:"system_prompt,omitempty"`
+}
+
+type BotModel struct {
+	ID                int64            `json:"id"`
+	Name              string           `json:"name"`
+	Version           string           `json:"version"`
+	IsActive          bool             `json:"is_active"`
+	Endpoint          string           `json:"endpoint,omitempty"`
+	ContextSize       int              `json:"context_size,omitempty"`
+	DefaultParams     GenerationParams `json:"default_params"`
+	DailyTokenQuota   *int64           `json:"daily_token_quota,omitempty"`
+	MonthlyTokenQuota *int64           `json:"monthly_token_quota,omitempty"`
+	CreatedAt         string           `json:"created_at"`
+	UpdatedAt         string           `json:"updated_at"`
+}
+
+type CreateModelReq struct {
+	Name              string           `json:"name"`
+	Version           string           `json:"version"`
+	Endpoint          string           `json:"endpoint"`
+	ContextSize       int              `json:"context_size"`
+	DefaultParams     GenerationParams `json:"default_params"`
+	DailyTokenQuota   *int64           `json:"daily_token_quota"`
+	MonthlyTokenQuota *int64           `json:"monthly_token_quota"`
+	IsActive          *bool            `json:"is_active"` // по умолчанию true
+}
+
+// name/version не меняются: на пару ссылаются чаты и клиенты.
+// nil = не менять; квота 0 = без ограничений.
+type UpdateModelReq struct {
+	Endpoint          *string           `json:"endpoint"`
+	ContextSize       *int              `json:"context_size"`
+	DefaultParams     *GenerationParams `json:"default_params"`
+	DailyTokenQuota   *int64            `json:"daily_token_quota"`
+	MonthlyTokenQuota *int64            `json:"monthly_token_quota"`
+}
+
+type ListModelsResp struct {
+	Items []BotModel `json:"items"`
+}
+
+// PublicModel — то, что видит выбор модели на фронте
+type PublicModel struct {
+	ID            int64            `json:"id"`
+	Name          string           `json:"name"`
+	Version       string           `json:"version"`
+	ContextSize   int              `json:"context_size,omitempty"`
+	DefaultParams GenerationParams `json:"default_params"`
+}
+
+type ListPublicModelsResp struct {
+	Items []PublicModel `json:"items"`
+}
+
+// ---------- /templates ----------
+type PromptTemplate struct {
+	ID        int64    `json:"id"`
+	UserID    int64    `json:"user_id"` // автор
+	Name      string   `json:"name"`
+	Content   string   `json:"content"`
+	Variables []string `json:"variables"` // {{name}} из content
+	IsShared  bool     `json:"is_shared"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// POST /templates — name и content обязательны; PATCH — nil = не менять
+type PromptTemplateReq struct {
+	UserID   int64   `json:"user_id"`
+	Name     *string `json:"name"`
+	Content  *string `json:"content"`
+	IsShared *bool   `json:"is_shared"`
+}
+
+type ListPromptTemplatesResp struct {
+	Items []PromptTemplate `json:"items"`
+}
+
+// ---------- GET /admin/models/{model_id}/feedback-stats ----------
+type FeedbackStatsFilter struct {
+	From    time.Time // включительно
+	To      time.Time // не включительно
+	Bucket  string    // day|week|month
+	Samples int       // сколько примеров на каждую оценку
+}
+
+type FeedbackCounts struct {
+	Positive     int64   `json:"positive"`
+	Negative     int64   `json:"negative"`
+	PositiveRate float64 `json:"positive_rate"` // 0, если оценок нет
+}
+
+type FeedbackBucket struct {
+	Start string `json:"start"`
+	FeedbackCounts
+}
+
+type FeedbackReason struct {
+	Reason string `json:"reason"`
+	FeedbackCounts
+}
+
+type FeedbackSample struct {
+	MessageUUID string `json:"message_uuid"`
+	ChatUUID    string `json:"chat_uuid"`
+	IsPositive  bool   `json:"is_positive"`
+	Reason      string `json:"reason,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+	Content     string `json:"content"` // ответ бота
+	CreatedAt   string `json:"created_at"`
+}
+
+type FeedbackStatsResp struct {
+	ModelID      int64            `json:"model_id"`
+	ModelName    string           `json:"model_name"`
+	ModelVersion string           `json:"model_version"`
+	From         string           `json:"from"`
+	To           string           `json:"to"`
+	Bucket       string           `json:"bucket"`
+	Total        FeedbackCounts   `json:"total"`
+	Buckets      []FeedbackBucket `json:"buckets"`
+	Reasons      []FeedbackReason `json:"reasons"`
+	Samples      []FeedbackSample `json:"samples"`
+}
+
+// ---------- dataset: GET /admin/dataset, cmd/dataset ----------
+type DatasetFilter struct {
+	ModelID      *int64
+	From         time.Time // zero = без ограничения
+	To           time.Time // zero = без ограничения, не включительно
+	Rating       string    // positive|negative|"" (все)
+	ContextTurns int       // сколько предыдущих сообщений чата брать в context
+	Redact       bool      // вырезать PII из текстов
+}
+
+type DatasetTurn struct {
+	Role    string `json:"role"` // user|bot
+	Content string `json:"content"`
+}
+
+// DatasetRecord — одна строка JSONL: оценённый ответ бота с запросом и контекстом
+type DatasetRecord struct {
+	MessageUUID  string        `json:"message_uuid"` // ответ бота
+	ModelName    string        `json:"model_name"`
+	ModelVersion string        `json:"model_version"`
+	Context      []DatasetTurn `json:"context"`
+	Prompt       string        `json:"prompt"`
+	Response     string        `json:"response"`
+	Rating       string        `json:"rating"` // positive|negative
+	Reason       string        `json:"reason,omitempty"`
+	RatedAt      string        `json:"rated_at"`
+}
+
+// ---------- usage ----------
+type UsageRecord struct {
+	UserID         int64
+	ModelID        int64
+	ChatUUID       string
+	MessageUUID    string // bot message
+	PromptChars    int
+	ResponseChars  int
+	PromptTokens   int
+	ResponseTokens int
+}
+
+// ---------- GET /usage?user_id=123 ----------
+type UsageCounters struct {
+	PromptChars    int64 `json:"prompt_chars"`
+	ResponseChars  int64 `json:"response_chars"`
+	PromptTokens   int64 `json:"prompt_tokens"`
+	ResponseTokens int64 `json:"response_tokens"`
+	TotalTokens    int64 `json:"total_tokens"`
+}
+
+type UsageItem struct {
+	ModelID      int64         `json:"model_id"`
+	ModelName    string        `json:"model_name"`
+	ModelVersion string        `json:"model_version"`
+	Daily        UsageCounters `json:"daily"`
+	Monthly      UsageCounters `json:"monthly"`
+	DailyQuota   *int64        `json:"daily_quota"`   // null = без ограничений
+	MonthlyQuota *int64        `json:"monthly_quota"` // null = без ограничений
+}
+
+type UsageResp struct {
+	UserID int64       `json:"user_id"`
+	Items  []UsageItem `json:"items"`
+}
+
+// ---------- purge ----------
+type PurgeReport struct {
+	Cutoff    string `json:"cutoff"`
+	DryRun    bool   `json:"dry_run"`
+	Chats     int64  `json:"chats"`
+	Messages  int64  `json:"messages"`
+	Feedbacks int64  `json:"feedbacks"`
+}
+
+// ---------- GDPR: GET /users/{user_id}/export, DELETE /users/{user_id}/data ----------
+type UserDataChat struct {
+	ChatUUID  string           `json:"chat_uuid"`
+	Title     string           `json:"title"`
+	ModelID   int64            `json:"model_id"`
+	Settings  GenerationParams `json:"settings"` // переопределения параметров генерации
+	IsDeleted bool             `json:"is_deleted"`
+	CreatedAt string           `json:"created_at"`
+	UpdatedAt string           `json:"updated_at"`
+	DeletedAt string           `json:"deleted_at,omitempty"`
+}
+
+type UserDataMessage struct {
+	MessageUUID      string `json:"message_uuid"`
+	ChatUUID         string `json:"chat_uuid"`
+	Role             string `json:"role"`
+	UserID           int64  `json:"user_id,omitempty"` // автор user-сообщения
+	Content          string `json:"content"`
+	ReplyToMessageID string `json:"reply_to_message_id,omitempty"`
+	IsDeleted        bool   `json:"is_deleted"`
+	CreatedAt        string `json:"created_at"`
+	DeletedAt        string `json:"deleted_at,omitempty"`
+}
+
+type UserDataFeedback struct {
+	MessageUUID string `json:"message_uuid"`
+	ModelID     int64  `json:"model_id"`
+	IsPositive  bool   `json:"is_positive"`
+	Reason      string `json:"reason,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// участие в чатах (в том числе в чужих)
+type UserDataMembership struct {
+	ChatUUID  string `json:"chat_uuid"`
+	Role      string `json:"role"`
+	InvitedBy int64  `json:"invited_by,omitempty"`
+	Pinned    bool   `json:"pinned"`
+	Archived  bool   `json:"archived"`
+	CreatedAt string `json:"created_at"`
+}
+
+// папка или тег вместе с размеченными чатами
+type UserDataLabel struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	ChatUUIDs []string `json:"chat_uuids"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type UserDataShare struct {
+	Token       string `json:"token"`
+	ChatUUID    string `json:"chat_uuid"`
+	SharedUntil string `json:"shared_until"`
+	CreatedAt   string `json:"created_at"`
+	RevokedAt   string `json:"revoked_at,omitempty"`
+}
+
+type UserDataTemplate struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Content   string `json:"content"`
+	IsShared  bool   `json:"is_shared"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type UserDataArenaPreference struct {
+	PromptMessageUUID string `json:"prompt_message_uuid"`
+	ChosenMessageUUID string `json:"chosen_message_uuid"`
+	ChosenModelID     int64  `json:"chosen_model_id"`
+	CreatedAt         string `json:"created_at"`
+	UpdatedAt         string `json:"updated_at"`
+}
+
+type UserDataUsage struct {
+	ChatUUID       string `json:"chat_uuid"`
+	MessageUUID    string `json:"message_uuid,omitempty"`
+	ModelID        int64  `json:"model_id"`
+	PromptChars    int    `json:"prompt_chars"`
+	ResponseChars  int    `json:"response_chars"`
+	PromptTokens   int    `json:"prompt_tokens"`
+	ResponseTokens int    `json:"response_tokens"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// всё, что хранится о пользователе (включая soft-deleted)
+type UserData struct {
+	UserID      int64                     `json:"user_id"`
+	Chats       []UserDataChat            `json:"chats"`
+	Messages    []UserDataMessage         `json:"messages"`
+	Memberships []UserDataMembership      `json:"memberships"`
+	Feedbacks   []UserDataFeedback        `json:"feedbacks"`
+	Usage       []UserDataUsage           `json:"usage"`
+	Folders     []UserDataLabel           `json:"folders"`
+	Tags        []UserDataLabel           `json:"tags"`
+	Shares      []UserDataShare           `json:"shares"`
+	Templates   []UserDataTemplate        `json:"templates"`
+	Arena       []UserDataArenaPreference `json:"arena_preferences"`
+}
+
+type EraseReport struct {
+	UserID           int64  `json:"user_id"`
+	Mode             string `json:"mode"` // delete|anonymize
+	Chats            int64  `json:"chats"`
+	TransferredChats int64  `json:"transferred_chats"` // общие чаты, переданные другому участнику
+	Messages         int64  `json:"messages"`
+	Feedbacks        int64  `json:"feedbacks"`
+	Usage            int64  `json:"usage"`
 }
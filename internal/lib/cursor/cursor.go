@@ -0,0 +1,46 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor — позиция в keyset-пагинации: (timestamp, uuid) последней отданной строки
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   string    `json:"id"`
+	// для списка чатов: закреплённые идут первыми
+	Pinned bool `json:"p,omitempty"`
+}
+
+// Encode упаковывает курсор в непрозрачную для клиента строку
+func Encode(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func Decode(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Cursor{}, ErrInvalid
+	}
+	// ID уходит в запрос как $n::uuid — мусор должен быть 400, а не 500
+	if c.Time.IsZero() {
+		return Cursor{}, ErrInvalid
+	}
+	if _, err := uuid.Parse(c.ID); err != nil {
+		return Cursor{}, ErrInvalid
+	}
+	return c, nil
+}
@@ -0,0 +1,45 @@
+package prompt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// плейсхолдер: {{name}} или {{ name }}; имя — буквы, цифры, _
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Variables — имена переменных шаблона в порядке первого появления, без повторов
+func Variables(tmpl string) []string {
+	seen := make(map[string]bool)
+	vars := make([]string, 0, 4)
+	for _, m := range placeholder.FindAllStringSubmatch(tmpl, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// Expand подставляет значения. missing — переменные без значения (отсортированы);
+// если он не пуст, текст не возвращается. Лишние значения игнорируются.
+// Подстановка за один проход: {{...}} внутри значений не раскрывается.
+func Expand(tmpl string, values map[string]string) (string, []string) {
+	var missing []string
+	for _, name := range Variables(tmpl) {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", missing
+	}
+
+	out := placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := strings.TrimSpace(m[2 : len(m)-2])
+		return values[name]
+	})
+	return out, nil
+}
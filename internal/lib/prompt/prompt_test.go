@@ -0,0 +1,98 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		want []string
+	}{
+		{"none", "plain text", []string{}},
+		{"order and dedup", "{{b}} {{a}} {{ b }}", []string{"b", "a"}},
+		{"spaces", "{{  lang  }}", []string{"lang"}},
+		{"invalid names", "{{1x}} {{a-b}} {{}} {single}", []string{}},
+		{"underscore and digits", "{{_x1}} {{text_2}}", []string{"_x1", "text_2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Variables(tt.tmpl); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variables(%q) = %v, want %v", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name        string
+		tmpl        string
+		values      map[string]string
+		want        string
+		wantMissing []string
+	}{
+		{
+			name:   "all values",
+			tmpl:   "Translate {{text}} to {{ lang }}",
+			values: map[string]string{"text": "hello", "lang": "French"},
+			want:   "Translate hello to French",
+		},
+		{
+			name:   "repeated variable",
+			tmpl:   "{{x}}+{{x}}",
+			values: map[string]string{"x": "1"},
+			want:   "1+1",
+		},
+		{
+			name:   "extra values ignored",
+			tmpl:   "hi {{name}}",
+			values: map[string]string{"name": "Ann", "unused": "x"},
+			want:   "hi Ann",
+		},
+		{
+			name:   "empty value is not missing",
+			tmpl:   "[{{x}}]",
+			values: map[string]string{"x": ""},
+			want:   "[]",
+		},
+		{
+			name:        "missing sorted, no text",
+			tmpl:        "{{b}} {{a}} {{c}}",
+			values:      map[string]string{"c": "1"},
+			wantMissing: []string{"a", "b"},
+		},
+		{
+			name:        "nil values",
+			tmpl:        "{{a}}",
+			wantMissing: []string{"a"},
+		},
+		{
+			name:   "no recursive expansion",
+			tmpl:   "{{a}} {{b}}",
+			values: map[string]string{"a": "{{b}}", "b": "B"},
+			want:   "{{b}} B",
+		},
+		{
+			name:   "invalid placeholders kept",
+			tmpl:   "{{1x}} {single} {{x}}",
+			values: map[string]string{"x": "ok"},
+			want:   "{{1x}} {single} ok",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, missing := Expand(tt.tmpl, tt.values)
+			if got != tt.want {
+				t.Errorf("Expand(%q) text = %q, want %q", tt.tmpl, got, tt.want)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("Expand(%q) missing = %v, want %v", tt.tmpl, missing, tt.wantMissing)
+			}
+		})
+	}
+}
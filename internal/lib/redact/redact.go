@@ -0,0 +1,69 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	re   *regexp.Regexp
+	repl string
+	// skip — совпадение s[start:end] по контексту не PII; nil — заменяем всегда
+	skip func(s string, start, end int) bool
+}
+
+// порядок важен, все правила кроме email — последовательности цифр:
+// международный телефон (с +) раньше карты, иначе длинный номер станет [CARD];
+// паспорт (4+6 цифр) раньше телефона
+var rules = []rule{
+	{re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), repl: "[EMAIL]"},
+	{re: regexp.MustCompile(`\+\d{1,3}(?:[ \-]?\(?\d{2,4}\)?){2,5}\b`), repl: "[PHONE]"},
+	{re: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), repl: "[CARD]"},
+	{re: regexp.MustCompile(`\b\d{2} ?\d{2} \d{6}\b`), repl: "[PASSPORT]"},
+	{re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`), repl: "[IP]", skip: notIP},
+	{re: regexp.MustCompile(`(?:\b[78][ \-]?\(?|\(|\b)\d{3}\)?[ \-]?\d{3}[ \-]?\d{2}[ \-]?\d{2}\b`), repl: "[PHONE]"},
+}
+
+// версия перед адресом: "version 1.2.3.4", "ver. 1.2.3.4"
+var versionPrefix = regexp.MustCompile(`(?i)\b(?:version|ver\.?|v)\s*$`)
+
+// notIP — четыре числа через точку, но это номер версии или кусок
+// более длинной последовательности (1.2.3.4.5)
+func notIP(s string, start, end int) bool {
+	if start > 0 && s[start-1] == '.' {
+		return true
+	}
+	if end+1 < len(s) && s[end] == '.' && s[end+1] >= '0' && s[end+1] <= '9' {
+		return true
+	}
+	return versionPrefix.MatchString(s[:start])
+}
+
+func (r rule) apply(s string) string {
+	locs := r.re.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		if r.skip != nil && r.skip(s, loc[0], loc[1]) {
+			continue
+		}
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(r.repl)
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// String заменяет похожие на PII фрагменты (email, телефон, номер карты,
+// паспорт, IP) на плейсхолдеры. Эвристика: лучше вырезать лишнее, чем пропустить.
+func String(s string) string {
+	for _, r := range rules {
+		s = r.apply(s)
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package redact
+
+import "testing"
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// email
+		{"email", "write to john.doe+ai@example.co.uk please", "write to [EMAIL] please"},
+
+		// телефоны
+		{"ru with 8", "call 8 912 345 67 89", "call [PHONE]"},
+		{"ru with +7 and brackets", "call +7 (912) 345-67-89 now", "call [PHONE] now"},
+		{"ru without prefix", "call 912-345-67-89", "call [PHONE]"},
+		{"ru plain digits", "tel 89123456789", "tel [PHONE]"},
+		{"uk", "call +44 20 7946 0958 today", "call [PHONE] today"},
+		{"de long", "mobile +49 1512 3456 7890", "mobile [PHONE]"},
+		{"us", "+1 415 555 2671", "[PHONE]"},
+
+		// карты
+		{"card spaced", "card 4111 1111 1111 1111 exp", "card [CARD] exp"},
+		{"card dashed", "4111-1111-1111-1111", "[CARD]"},
+		{"card plain", "5500000000000004", "[CARD]"},
+
+		// паспорт
+		{"passport", "passport 4510 123456", "passport [PASSPORT]"},
+		{"passport split series", "паспорт 45 10 123456", "паспорт [PASSPORT]"},
+
+		// IP
+		{"ip", "server 192.168.0.1 is down", "server [IP] is down"},
+		{"ip end of sentence", "ping 10.0.0.1.", "ping [IP]."},
+		{"ip version", "version 1.2.3.4", "version 1.2.3.4"},
+		{"ip ver.", "ver. 1.2.3.4", "ver. 1.2.3.4"},
+		{"ip v", "v 10.0.0.1 released", "v 10.0.0.1 released"},
+		{"ip bad octet", "build 300.1.2.3", "build 300.1.2.3"},
+		{"ip five parts", "oid 1.3.6.1.4", "oid 1.3.6.1.4"},
+
+		// не PII
+		{"year", "in 2024 we", "in 2024 we"},
+		{"date", "on 2024-01-15", "on 2024-01-15"},
+		{"short number", "order 123456", "order 123456"},
+		{"price", "costs 1 500 rub", "costs 1 500 rub"},
+		{"plain text", "hello, world", "hello, world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.in); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
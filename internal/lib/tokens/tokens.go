@@ -0,0 +1,21 @@
+package tokens
+
+import "unicode/utf8"
+
+// charsPerToken — средняя длина токена для BPE-токенизаторов
+const charsPerToken = 4
+
+// Chars возвращает длину строки в символах (а не байтах)
+func Chars(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// Estimate грубо оценивает число токенов в строке.
+// Используется, когда нейросервис не прислал точные значения.
+func Estimate(s string) int {
+	n := Chars(s)
+	if n == 0 {
+		return 0
+	}
+	return (n + charsPerToken - 1) / charsPerToken
+}
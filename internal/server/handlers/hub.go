@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const writeWait = 10 * time.Second
+
+// wsConn — соединение с мьютексом на запись: кроме read loop в conn
+// теперь пишет и Hub (события из HTTP API)
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *wsConn) WriteJSON(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.Conn.WriteJSON(v)
+}
+
+// Hub хранит открытые ws-соединения по user_id для рассылки событий
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[int64]map[*wsConn]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{conns: make(map[int64]map[*wsConn]struct{})}
+}
+
+func (h *Hub) add(userID int64, c *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.conns[userID] == nil {
+		h.conns[userID] = make(map[*wsConn]struct{})
+	}
+	h.conns[userID][c] = struct{}{}
+}
+
+func (h *Hub) remove(userID int64, c *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.conns[userID], c)
+	if len(h.conns[userID]) == 0 {
+		delete(h.conns, userID)
+	}
+}
+
+// NotifyUser отправляет событие во все открытые сессии пользователя
+func (h *Hub) NotifyUser(userID int64, v any) {
+	h.notify(userID, v, nil)
+}
+
+// notify — как NotifyUser, но без соединения skip (отправитель уже знает о событии)
+func (h *Hub) notify(userID int64, v any, skip *wsConn) {
+	h.mu.RLock()
+	conns := make([]*wsConn, 0, len(h.conns[userID]))
+	for c := range h.conns[userID] {
+		if c != skip {
+			conns = append(conns, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range conns {
+		if err := c.WriteJSON(v); err != nil {
+			// соединение закроется в своём read loop
+			log.Printf("Hub.NotifyUser: write error: %v", err)
+		}
+	}
+}
@@ -3,12 +3,21 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"MicroserviceWebsocket/internal/config"
 	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/prompt"
+	"MicroserviceWebsocket/internal/lib/tokens"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
 	_ "MicroserviceWebsocket/internal/services/batch"
 	"MicroserviceWebsocket/internal/services/neural"
 
@@ -19,16 +28,32 @@ import (
 const (
 	pongWait   = 20 * time.Second
 	pingPeriod = 15 * time.Second
+
+	maxArenaModels = 4
 )
 
 type Storage interface {
-	InsertUserMessage(ctx context.Context, chatUUID, messageUUID, content string) error
-	InsertBotMessage(ctx context.Context, chatUUID, messageUUID, content, replyToUUID string) error
+	InsertUserMessage(ctx context.Context, userID int64, chatUUID, messageUUID, content string) error
+	SaveBotTurn(ctx context.Context, turn models.BotTurn) error
+	CheckChatAccess(ctx context.Context, userID int64, chatUUID string) (int64, error)
+	ActiveModelID(ctx context.Context, name, version string) (int64, error)
+	CreateChatWithUserMessage(ctx context.Context, userID, modelID int64, chatUUID, messageUUID, content string) (string, bool, error)
+	CheckQuota(ctx context.Context, userID, modelID int64, promptTokens int) error
+	ModelNameExists(ctx context.Context, name string) (bool, error)
+	GenerationSettings(ctx context.Context, chatUUID string) (models.ChatSettingsResp, error)
+	SwitchChatModel(ctx context.Context, userID int64, chatUUID string, modelID int64) (models.ChatModelResp, error)
+	ArenaSettings(ctx context.Context, chatUUID string, modelIDs []int64) ([]models.ChatSettingsResp, error)
+	GetPromptTemplate(ctx context.Context, userID, templateID int64) (models.PromptTemplate, error)
+	ChatMemberIDs(ctx context.Context, chatUUID string) ([]int64, error)
 }
 
 type WebSocketHandler struct {
-	neuralClient *neural.Client
+	neuralClient *neural.Pool
 	storage      Storage
+	hub          *Hub
+
+	maxFrameSize    int64
+	maxPromptLength int
 }
 
 var upgrader = websocket.Upgrader{
@@ -40,8 +65,14 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func NewWebSocketHandler(neuralClient *neural.Client, storage Storage) *WebSocketHandler {
-	return &WebSocketHandler{neuralClient: neuralClient, storage: storage}
+func NewWebSocketHandler(neuralClient *neural.Pool, storage Storage, cfg config.WebSocket, hub *Hub) *WebSocketHandler {
+	return &WebSocketHandler{
+		neuralClient:    neuralClient,
+		storage:         storage,
+		hub:             hub,
+		maxFrameSize:    cfg.MaxFrameSize,
+		maxPromptLength: cfg.MaxPromptLength,
+	}
 }
 
 // func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
@@ -97,12 +128,32 @@ func NewWebSocketHandler(neuralClient *neural.Client, storage Storage) *WebSocke
 func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	const op = "WebSocketHandler.HandleConnection"
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// user_id пока берём из query, как и в HTTP API (потом из токена).
+	// Обязателен: старые клиенты без ?user_id получают 400 вместо апгрейда
+	userID, err := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+	if err != nil || userID <= 0 {
+		http.Error(w, "query user_id is required and must be int64", http.StatusBadRequest)
+		return
+	}
+
+	rawConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("%s: upgrade error: %v", op, err)
 		return
 	}
-	defer conn.Close()
+	defer rawConn.Close()
+
+	// все записи в conn идут через мьютекс wsConn
+	conn := &wsConn{Conn: rawConn}
+	h.hub.add(userID, conn)
+	defer h.hub.remove(userID, conn)
+
+	// слишком большой фрейм -> ReadMessage вернёт ErrReadLimit и соединение закроется
+	// с 1009 (message too big) без validation_error: фрейм не дочитан, отвечать не на что.
+	// Это контракт для клиента; лимит с запасом над max_prompt_length (см. config)
+	if h.maxFrameSize > 0 {
+		conn.SetReadLimit(h.maxFrameSize)
+	}
 
 	// канал завершения — остановит ping-горутину
 	done := make(chan struct{})
@@ -151,8 +202,8 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 			return
 		}
 
-		// важно: без go, чтобы не было гонок записи в conn
-		h.handleMessage(conn, message)
+		// обрабатываем последовательно, чтобы ответы шли в порядке сообщений
+		h.handleMessage(conn, userID, message)
 	}
 }
 
@@ -184,42 +235,188 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 // 	conn.WriteJSON(result)
 // }
 
-func (h *WebSocketHandler) handleMessage(conn *websocket.Conn, msg []byte) {
+func (h *WebSocketHandler) handleMessage(conn *wsConn, userID int64, msg []byte) {
 	const op = "WebSocketHandler.handleMessage"
 
+	// команды с полем type; без type — обычное сообщение в чат
+	var cmd struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(msg, &cmd); err == nil && cmd.Type != "" {
+		switch cmd.Type {
+		case "switch_model":
+			h.handleSwitchModel(conn, userID, msg)
+		default:
+			writeError(conn, "unknown_type", "unknown message type")
+		}
+		return
+	}
+
 	request, err := validateMessage(string(msg))
 	if err != nil {
 		log.Print(fmt.Errorf("%w: %s", err, op))
+		writeError(conn, "bad_json", "message must be a valid json object")
 		return
 	}
 
-	// validate UUIDs
-	if _, err := uuid.Parse(request.ChatUUID); err != nil {
-		_ = conn.WriteJSON(map[string]any{"error": "validation_error", "msg": "chat_uuid must be uuid"})
+	// template_id: раскрываем шаблон до валидации — длину проверяем у итогового текста
+	if request.TemplateID != nil {
+		fields, err := h.expandTemplate(context.Background(), userID, &request)
+		if err != nil {
+			writeError(conn, "db_error", err.Error())
+			return
+		}
+		if len(fields) > 0 {
+			writeValidationError(conn, fields)
+			return
+		}
+	}
+
+	fields, err := h.validateRequest(context.Background(), request)
+	if err != nil {
+		writeError(conn, "db_error", err.Error())
 		return
 	}
-	if _, err := uuid.Parse(request.UUID); err != nil {
-		_ = conn.WriteJSON(map[string]any{"error": "validation_error", "msg": "uuid must be uuid"})
+	if len(fields) > 0 {
+		writeValidationError(conn, fields)
+		return
+	}
+
+	// 0) chat: существует, не удалён и пользователь может в него писать (editor+).
+	// Если чата нет и пришла model_version — создадим его вместе с первым сообщением.
+	newChat := false
+	modelID, err := h.storage.CheckChatAccess(context.Background(), userID, request.ChatUUID)
+	if errors.Is(err, httpAPI.ErrChatNotFound) && request.ModelVersion != "" {
+		newChat = true
+		modelID, err = h.storage.ActiveModelID(context.Background(), request.ModelName, request.ModelVersion)
+		if errors.Is(err, httpAPI.ErrModelNotFound) {
+			writeValidationError(conn, []models.FieldError{{Field: "model_version", Msg: "unknown model version"}})
+			return
+		}
+	}
+	if err != nil {
+		writeChatError(conn, err)
+		return
+	}
+
+	// модель и параметры генерации читаем один раз до квоты: участник мог сменить
+	// модель чата, и квота, генерация, сохранение и учёт должны видеть одну и ту же.
+	// У нового чата — после его создания (модель уже известна из ActiveModelID)
+	var gen models.ChatSettingsResp
+	if !newChat && len(request.ArenaModelIDs) == 0 {
+		gen, err = h.storage.GenerationSettings(context.Background(), request.ChatUUID)
+		if err != nil {
+			writeError(conn, "db_error", err.Error())
+			return
+		}
+		modelID = gen.ModelID
+	}
+
+	// arena: отвечают выбранные модели, а не модель чата
+	var arena []models.ChatSettingsResp
+	quotaModels := []int64{modelID}
+	if len(request.ArenaModelIDs) > 0 {
+		if newChat {
+			writeValidationError(conn, []models.FieldError{{Field: "arena_model_ids", Msg: "chat must exist"}})
+			return
+		}
+		arena, err = h.storage.ArenaSettings(context.Background(), request.ChatUUID, request.ArenaModelIDs)
+		if errors.Is(err, httpAPI.ErrModelNotFound) {
+			writeValidationError(conn, []models.FieldError{{Field: "arena_model_ids", Msg: "unknown or inactive model"}})
+			return
+		}
+		if err != nil {
+			writeError(conn, "db_error", err.Error())
+			return
+		}
+		quotaModels = request.ArenaModelIDs
+	}
+
+	// quota: проверяем до обращения к нейросервису
+	for _, id := range quotaModels {
+		if err := h.storage.CheckQuota(context.Background(), userID, id, tokens.Estimate(request.Message)); err != nil {
+			if errors.Is(err, httpAPI.ErrQuotaExceeded) {
+				writeError(conn, "quota_exceeded", "token quota exceeded for this model")
+				return
+			}
+			writeError(conn, "db_error", err.Error())
+			return
+		}
+	}
+
+	// 1) save user message (и чат, если он новый)
+	if newChat {
+		title, created, err := h.storage.CreateChatWithUserMessage(
+			context.Background(), userID, modelID, request.ChatUUID, request.UUID, request.Message,
+		)
+		if err != nil {
+			writeChatError(conn, err)
+			return
+		}
+		if created {
+			if err := conn.WriteJSON(models.WSChatCreated{
+				Type:         "chat_created",
+				ChatUUID:     request.ChatUUID,
+				Title:        title,
+				ModelID:      modelID,
+				ModelName:    request.ModelName,
+				ModelVersion: request.ModelVersion,
+			}); err != nil {
+				log.Printf("write ws json error: %v", err)
+			}
+		}
+	} else if err := h.storage.InsertUserMessage(context.Background(), userID, request.ChatUUID, request.UUID, request.Message); err != nil {
+		writeError(conn, "db_error", err.Error())
 		return
 	}
 
-	// 1) save user message
-	if err := h.storage.InsertUserMessage(context.Background(), request.ChatUUID, request.UUID, request.Message); err != nil {
-		_ = conn.WriteJSON(map[string]any{"error": "db_error", "msg": err.Error()})
+	// остальные участники (и другие вкладки отправителя) видят новый промпт
+	h.broadcast(conn, request.ChatUUID, models.WSUserMessage{
+		Type:        "user_message",
+		ChatUUID:    request.ChatUUID,
+		MessageUUID: request.UUID,
+		UserID:      userID,
+		Content:     request.Message,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}, conn)
+
+	if len(arena) > 0 {
+		h.runArena(conn, userID, request, arena)
 		return
 	}
 
-	// 2) neural
-	result, err := h.neuralClient.ProcessSingle(request)
+	// 2) neural: модель и параметры генерации — из чата (см. выше)
+	if newChat {
+		gen, err = h.storage.GenerationSettings(context.Background(), request.ChatUUID)
+		if err != nil {
+			writeError(conn, "db_error", err.Error())
+			return
+		}
+	}
+	request.ModelName = gen.ModelName
+	request.Settings = &gen.Effective
+
+	started := time.Now()
+	result, err := h.neuralClient.ProcessSingle(gen.Endpoint, request)
 	if err != nil {
-		_ = conn.WriteJSON(map[string]any{"error": "neural_error", "msg": err.Error()})
+		writeError(conn, "neural_error", err.Error())
 		return
 	}
+	latency := time.Since(started)
 
-	// 3) save bot message
+	// 3) save bot message + usage одной транзакцией
 	botUUID := uuid.NewString()
-	if err := h.storage.InsertBotMessage(context.Background(), request.ChatUUID, botUUID, result.Response, request.UUID); err != nil {
-		_ = conn.WriteJSON(map[string]any{"error": "db_error", "msg": err.Error()})
+	turn := models.BotTurn{
+		ChatUUID:    request.ChatUUID,
+		MessageUUID: botUUID,
+		ReplyToUUID: request.UUID,
+		Content:     result.Response,
+		ModelID:     gen.ModelID,
+		LatencyMs:   latency.Milliseconds(),
+		Usage:       usageRecord(userID, gen.ModelID, request, botUUID, result),
+	}
+	if err := h.storage.SaveBotTurn(context.Background(), turn); err != nil {
+		writeError(conn, "db_error", err.Error())
 		return
 	}
 
@@ -232,11 +429,233 @@ func (h *WebSocketHandler) handleMessage(conn *websocket.Conn, msg []byte) {
 		CreatedAt:       result.CreatedAt,
 	}
 
-	if err := conn.WriteJSON(resp); err != nil {
+	// ответ получают все участники чата, включая отправителя
+	h.broadcast(conn, request.ChatUUID, resp, nil)
+}
+
+// runArena отправляет промпт во все модели arena параллельно; каждый ответ —
+// отдельное bot-сообщение с reply_to на тот же промпт и своим model_id.
+// Ответы уходят всем участникам чата по мере готовности, выход — когда ответили все.
+func (h *WebSocketHandler) runArena(conn *wsConn, userID int64, request models.Request, arena []models.ChatSettingsResp) {
+	var wg sync.WaitGroup
+	for _, gen := range arena {
+		wg.Add(1)
+		go func(gen models.ChatSettingsResp) {
+			defer wg.Done()
+
+			// uuid запроса в gateway должен быть уникален среди ожидающих — берём uuid ответа
+			botUUID := uuid.NewString()
+			req := request
+			req.UUID = botUUID
+			req.ModelName = gen.ModelName
+			req.Settings = &gen.Effective
+
+			started := time.Now()
+			result, err := h.neuralClient.ProcessSingle(gen.Endpoint, req)
+			if err != nil {
+				writeError(conn, "neural_error", fmt.Sprintf("%s: %v", gen.ModelName, err))
+				return
+			}
+			latency := time.Since(started)
+
+			turn := models.BotTurn{
+				ChatUUID:    request.ChatUUID,
+				MessageUUID: botUUID,
+				ReplyToUUID: request.UUID,
+				Content:     result.Response,
+				ModelID:     gen.ModelID,
+				LatencyMs:   latency.Milliseconds(),
+				Usage:       usageRecord(userID, gen.ModelID, request, botUUID, result),
+			}
+			if err := h.storage.SaveBotTurn(context.Background(), turn); err != nil {
+				writeError(conn, "db_error", err.Error())
+				return
+			}
+
+			h.broadcast(conn, request.ChatUUID, models.WSBotMessage{
+				Type:            "bot_message",
+				ChatUUID:        request.ChatUUID,
+				UserMessageUUID: request.UUID,
+				BotMessageUUID:  botUUID,
+				Response:        result.Response,
+				CreatedAt:       result.CreatedAt,
+				ModelID:         gen.ModelID,
+				ModelName:       gen.ModelName,
+			}, nil)
+		}(gen)
+	}
+	wg.Wait()
+}
+
+func usageRecord(userID, modelID int64, request models.Request, botUUID string, result models.Response) models.UsageRecord {
+	promptTokens := result.PromptTokens
+	if promptTokens == 0 {
+		promptTokens = tokens.Estimate(request.Message)
+	}
+	responseTokens := result.ResponseTokens
+	if responseTokens == 0 {
+		responseTokens = tokens.Estimate(result.Response)
+	}
+
+	return models.UsageRecord{
+		UserID:         userID,
+		ModelID:        modelID,
+		ChatUUID:       request.ChatUUID,
+		MessageUUID:    botUUID,
+		PromptChars:    tokens.Chars(request.Message),
+		ResponseChars:  tokens.Chars(result.Response),
+		PromptTokens:   promptTokens,
+		ResponseTokens: responseTokens,
+	}
+}
+
+// handleSwitchModel — {"type":"switch_model"}: следующие ответы генерирует другая модель
+func (h *WebSocketHandler) handleSwitchModel(conn *wsConn, userID int64, msg []byte) {
+	var req models.WSSwitchModel
+	if err := json.Unmarshal(msg, &req); err != nil {
+		writeError(conn, "bad_json", "message must be a valid json object")
+		return
+	}
+
+	var fields []models.FieldError
+	if _, err := uuid.Parse(req.ChatUUID); err != nil {
+		fields = append(fields, models.FieldError{Field: "chat_uuid", Msg: "must be uuid"})
+	}
+	if req.ModelID <= 0 {
+		fields = append(fields, models.FieldError{Field: "model_id", Msg: "is required"})
+	}
+	if len(fields) > 0 {
+		writeValidationError(conn, fields)
+		return
+	}
+
+	resp, err := h.storage.SwitchChatModel(context.Background(), userID, req.ChatUUID, req.ModelID)
+	if errors.Is(err, httpAPI.ErrModelNotFound) {
+		writeValidationError(conn, []models.FieldError{{Field: "model_id", Msg: "unknown or inactive model"}})
+		return
+	}
+	if err != nil {
+		writeChatError(conn, err)
+		return
+	}
+
+	if err := conn.WriteJSON(models.WSModelSwitched{Type: "model_switched", ChatModelResp: resp}); err != nil {
 		log.Printf("write ws json error: %v", err)
 	}
 }
 
+// expandTemplate подставляет переменные в шаблон и кладёт текст в request.Message;
+// в чат сохраняется уже раскрытый текст
+func (h *WebSocketHandler) expandTemplate(ctx context.Context, userID int64, request *models.Request) ([]models.FieldError, error) {
+	if request.Message != "" {
+		return []models.FieldError{{Field: "message", Msg: "must be empty when template_id is set"}}, nil
+	}
+
+	tmpl, err := h.storage.GetPromptTemplate(ctx, userID, *request.TemplateID)
+	if errors.Is(err, httpAPI.ErrTemplateNotFound) {
+		return []models.FieldError{{Field: "template_id", Msg: "template not found"}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	text, missing := prompt.Expand(tmpl.Content, request.Variables)
+	if len(missing) > 0 {
+		return []models.FieldError{{Field: "variables", Msg: "missing values: " + strings.Join(missing, ", ")}}, nil
+	}
+
+	request.Message = text
+	return nil, nil
+}
+
+// validateRequest собирает все ошибки полей сразу, чтобы клиент мог показать их вместе
+func (h *WebSocketHandler) validateRequest(ctx context.Context, request models.Request) ([]models.FieldError, error) {
+	var fields []models.FieldError
+
+	if _, err := uuid.Parse(request.ChatUUID); err != nil {
+		fields = append(fields, models.FieldError{Field: "chat_uuid", Msg: "must be uuid"})
+	}
+	if _, err := uuid.Parse(request.UUID); err != nil {
+		fields = append(fields, models.FieldError{Field: "uuid", Msg: "must be uuid"})
+	}
+
+	if strings.TrimSpace(request.Message) == "" {
+		fields = append(fields, models.FieldError{Field: "message", Msg: "must not be empty"})
+	} else if h.maxPromptLength > 0 && utf8.RuneCountInString(request.Message) > h.maxPromptLength {
+		fields = append(fields, models.FieldError{Field: "message", Msg: fmt.Sprintf("must be at most %d characters", h.maxPromptLength)})
+	}
+
+	if n := len(request.ArenaModelIDs); n > 0 {
+		if n < 2 || n > maxArenaModels {
+			fields = append(fields, models.FieldError{Field: "arena_model_ids", Msg: fmt.Sprintf("must contain 2..%d models", maxArenaModels)})
+		} else {
+			seen := make(map[int64]bool, n)
+			for _, id := range request.ArenaModelIDs {
+				if id <= 0 || seen[id] {
+					fields = append(fields, models.FieldError{Field: "arena_model_ids", Msg: "must be distinct model ids"})
+					break
+				}
+				seen[id] = true
+			}
+		}
+	}
+
+	// в существующем чате модель берётся из чата; model_name нужен только для нового
+	if request.ModelName == "" {
+		if request.ModelVersion != "" {
+			fields = append(fields, models.FieldError{Field: "model_name", Msg: "is required"})
+		}
+	} else {
+		ok, err := h.storage.ModelNameExists(ctx, request.ModelName)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			fields = append(fields, models.FieldError{Field: "model_name", Msg: "unknown model"})
+		}
+	}
+
+	return fields, nil
+}
+
+// broadcast рассылает событие чата во все сессии его участников, кроме skip.
+// Не удалось получить участников — событие получит только conn.
+func (h *WebSocketHandler) broadcast(conn *wsConn, chatUUID string, v any, skip *wsConn) {
+	members, err := h.storage.ChatMemberIDs(context.Background(), chatUUID)
+	if err != nil {
+		log.Printf("list chat members for broadcast failed: %v", err)
+		if conn != skip {
+			if err := conn.WriteJSON(v); err != nil {
+				log.Printf("write ws json error: %v", err)
+			}
+		}
+		return
+	}
+	for _, id := range members {
+		h.hub.notify(id, v, skip)
+	}
+}
+
+func writeError(conn *wsConn, code, msg string) {
+	_ = conn.WriteJSON(models.WSError{Error: code, Msg: msg})
+}
+
+// writeChatError переводит ошибки доступа к чату в типизированные ws-фреймы
+func writeChatError(conn *wsConn, err error) {
+	switch {
+	case errors.Is(err, httpAPI.ErrChatNotFound):
+		writeError(conn, "chat_not_found", "chat not found")
+	case errors.Is(err, httpAPI.ErrForbidden):
+		writeError(conn, "forbidden", "not enough permissions for this chat")
+	default:
+		writeError(conn, "db_error", err.Error())
+	}
+}
+
+func writeValidationError(conn *wsConn, fields []models.FieldError) {
+	_ = conn.WriteJSON(models.WSError{Error: "validation_error", Msg: "invalid fields", Fields: fields})
+}
+
 func validateMessage(msgStr string) (models.Request, error) {
 	var request models.Request
 	err := json.Unmarshal([]byte(msgStr), &request)
@@ -0,0 +1,265 @@
+package http
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/logger/sl"
+	"MicroserviceWebsocket/internal/services/dataset"
+
+	"golang.org/x/exp/slog"
+)
+
+const (
+	defaultStatsRange   = 30 * 24 * time.Hour
+	defaultStatsSamples = 5
+	maxStatsSamples     = 50
+
+	defaultDatasetContext = 6
+	maxDatasetContext     = 50
+)
+
+// authorizeAdmin проверяет Authorization: Bearer <admin token>.
+// Пустой токен в конфиге = admin API выключен.
+func (a *API) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if a.adminToken == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) != 1 {
+		writeErr(w, http.StatusUnauthorized, "unauthorized", "admin token required")
+		return false
+	}
+	return true
+}
+
+// /admin/... -> admin-only routes
+func (a *API) Admin(w http.ResponseWriter, r *http.Request) {
+	if !a.authorizeAdmin(w, r) {
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/admin/")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch parts[0] {
+	case "users":
+		// /admin/users/{user_id}/export, /admin/users/{user_id}/data
+		a.userData(w, r, parts[1:], true)
+	case "models":
+		// /admin/models/{id}/feedback-stats
+		a.adminModels(w, r, parts[1:])
+	case "dataset":
+		// /admin/dataset?model_id=&from=&to=&rating=&context=&redact=
+		if len(parts) != 1 {
+			writeErr(w, http.StatusNotFound, "not_found", "not found")
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.exportDataset(w, r)
+	default:
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+	}
+}
+
+// /admin/models, /admin/models/{id}, /admin/models/{id}/activate|deactivate|feedback-stats
+func (a *API) adminModels(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 || parts[0] == "" {
+		switch r.Method {
+		case http.MethodGet:
+			a.listModels(w, r)
+		case http.MethodPost:
+			a.createModel(w, r)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
+		return
+	}
+
+	modelID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || modelID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "model_id must be int64")
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			a.getModel(w, r, modelID)
+		case http.MethodPatch:
+			a.updateModel(w, r, modelID)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
+		return
+	}
+	if len(parts) != 2 {
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+
+	switch parts[1] {
+	case "activate", "deactivate":
+		if r.Method != http.MethodPost {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.setModelActive(w, r, modelID, parts[1] == "activate")
+	case "feedback-stats":
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.feedbackStats(w, r, modelID)
+	default:
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+	}
+}
+
+// parseTimeParam принимает RFC3339 или YYYY-MM-DD (полночь UTC)
+func parseTimeParam(s string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	return t, false, err
+}
+
+// GET /admin/models/{id}/feedback-stats?from=&to=&bucket=day|week|month&samples=5
+// по умолчанию — последние 30 дней по дням
+func (a *API) feedbackStats(w http.ResponseWriter, r *http.Request, modelID int64) {
+	q := r.URL.Query()
+
+	f := models.FeedbackStatsFilter{
+		To:      time.Now().UTC(),
+		Bucket:  "day",
+		Samples: defaultStatsSamples,
+	}
+	if s := q.Get("to"); s != "" {
+		to, dateOnly, err := parseTimeParam(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "to must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		// дата без времени — весь этот день включительно
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		f.To = to
+	}
+	f.From = f.To.Add(-defaultStatsRange)
+	if s := q.Get("from"); s != "" {
+		from, _, err := parseTimeParam(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "from must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		f.From = from
+	}
+	if !f.From.Before(f.To) {
+		writeErr(w, http.StatusBadRequest, "validation_error", "from must be before to")
+		return
+	}
+	if s := q.Get("bucket"); s != "" {
+		switch s {
+		case "day", "week", "month":
+			f.Bucket = s
+		default:
+			writeErr(w, http.StatusBadRequest, "validation_error", "bucket must be day, week or month")
+			return
+		}
+	}
+	if s := q.Get("samples"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > maxStatsSamples {
+			writeErr(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("samples must be 0..%d", maxStatsSamples))
+			return
+		}
+		f.Samples = n
+	}
+
+	resp, err := a.svc.FeedbackStats(r.Context(), modelID, f)
+	if err != nil {
+		switch err {
+		case ErrModelNotFound:
+			writeErr(w, http.StatusNotFound, "model_not_found", "model not found")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GET /admin/dataset — оценённые ответы как JSONL для обучения
+func (a *API) exportDataset(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	f := models.DatasetFilter{ContextTurns: defaultDatasetContext}
+	if s := q.Get("model_id"); s != "" {
+		modelID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || modelID <= 0 {
+			writeErr(w, http.StatusBadRequest, "validation_error", "model_id must be int64")
+			return
+		}
+		f.ModelID = &modelID
+	}
+	if s := q.Get("from"); s != "" {
+		from, _, err := parseTimeParam(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "from must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		f.From = from
+	}
+	if s := q.Get("to"); s != "" {
+		to, dateOnly, err := parseTimeParam(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "to must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		f.To = to
+	}
+	switch s := q.Get("rating"); s {
+	case "", "positive", "negative":
+		f.Rating = s
+	default:
+		writeErr(w, http.StatusBadRequest, "validation_error", "rating must be positive or negative")
+		return
+	}
+	if s := q.Get("context"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > maxDatasetContext {
+			writeErr(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("context must be 0..%d", maxDatasetContext))
+			return
+		}
+		f.ContextTurns = n
+	}
+	if s := q.Get("redact"); s != "" {
+		redact, err := strconv.ParseBool(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "redact must be true or false")
+			return
+		}
+		f.Redact = redact
+	}
+
+	a.extendWriteDeadline(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+dataset.FileName(time.Now())+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	if n, err := dataset.WriteJSONL(r.Context(), w, a.svc, f); err != nil {
+		// статус уже отправлен — остаётся только лог
+		a.log.Error("dataset export failed", slog.Int("written", n), sl.Err(err))
+	}
+}
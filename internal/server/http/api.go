@@ -9,32 +9,114 @@ import (
 
 	"golang.org/x/exp/slog"
 
+	"MicroserviceWebsocket/internal/config"
 	models "MicroserviceWebsocket/internal/domain"
 )
 
 var (
-	ErrModelNotFound   = errors.New("model not found")
-	ErrChatNotFound    = errors.New("chat not found")
-	ErrMessageNotFound = errors.New("message not found")
-	ErrForbidden       = errors.New("forbidden")
-	ErrNotBotMessage   = errors.New("not bot message")
+	ErrModelNotFound    = errors.New("model not found")
+	ErrChatNotFound     = errors.New("chat not found")
+	ErrMessageNotFound  = errors.New("message not found")
+	ErrForbidden        = errors.New("forbidden")
+	ErrNotBotMessage    = errors.New("not bot message")
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+	ErrBadCursor        = errors.New("bad cursor")
+	ErrNotDeleted       = errors.New("not deleted")
+	ErrChatExists       = errors.New("chat already exists")
+	ErrMessageExists    = errors.New("message already exists")
+	ErrModelExists      = errors.New("model already exists")
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrShareNotFound    = errors.New("share not found")
+	ErrMemberNotFound   = errors.New("member not found")
+	ErrFolderNotFound   = errors.New("folder not found")
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrNameTaken        = errors.New("name already taken")
+	ErrFeedbackNotFound = errors.New("feedback not found")
+	ErrNotArenaMessage  = errors.New("message is not an arena answer")
 )
 
 type Storage interface {
 	CreateChat(ctx context.Context, req models.CreateChatReq) (models.CreateChatResp, error)
-	ListChats(ctx context.Context, userID int64) (models.ListChatsResp, error)
-	ListMessages(ctx context.Context, userID int64, chatID string) (models.ListMessagesResp, error)
+	ListChats(ctx context.Context, userID int64, filter models.ChatsFilter, page models.PageReq) (models.ListChatsResp, error)
+	ImportChat(ctx context.Context, userID int64, chat models.ImportChat) error
+	UpdateChat(ctx context.Context, chatID string, req models.UpdateChatReq) (models.ChatItem, error)
+	ListMessages(ctx context.Context, userID int64, chatID string, page models.PageReq) (models.ListMessagesResp, error)
+	ChatInfo(ctx context.Context, userID int64, chatID string) (models.ChatInfo, error)
+	EachMessage(ctx context.Context, userID int64, chatID string, fn func(models.MessageItem) error) error
 	DeleteChat(ctx context.Context, userID int64, chatID string) error
-	SetFeedback(ctx context.Context, messageID string, userID int64, isPositive bool) (models.FeedbackResp, error)
+	ListMembers(ctx context.Context, userID int64, chatID string) (models.ListMembersResp, error)
+	ChatMemberIDs(ctx context.Context, chatUUID string) ([]int64, error)
+	AddMember(ctx context.Context, req models.AddMemberReq, chatID string) (models.ChatMember, error)
+	RemoveMember(ctx context.Context, userID int64, chatID string, memberID int64) error
+	ListFolders(ctx context.Context, userID int64) (models.ListLabelsResp, error)
+	CreateFolder(ctx context.Context, userID int64, name string) (models.Label, error)
+	RenameFolder(ctx context.Context, userID, folderID int64, name string) (models.Label, error)
+	DeleteFolder(ctx context.Context, userID, folderID int64) error
+	SetChatFolder(ctx context.Context, userID int64, chatID string, folderID *int64) error
+	ListTags(ctx context.Context, userID int64) (models.ListLabelsResp, error)
+	CreateTag(ctx context.Context, userID int64, name string) (models.Label, error)
+	RenameTag(ctx context.Context, userID, tagID int64, name string) (models.Label, error)
+	DeleteTag(ctx context.Context, userID, tagID int64) error
+	AddChatTag(ctx context.Context, userID int64, chatID string, tagID int64) error
+	RemoveChatTag(ctx context.Context, userID int64, chatID string, tagID int64) error
+	CreateShare(ctx context.Context, userID int64, chatID string) (models.ShareResp, error)
+	RevokeShares(ctx context.Context, userID int64, chatID string) error
+	SharedChat(ctx context.Context, token string, page models.PageReq) (models.SharedChatResp, error)
+	RestoreChat(ctx context.Context, userID int64, chatID string) error
+	DeleteMessage(ctx context.Context, messageID string, userID int64) (models.DeleteMessageResp, error)
+	SetFeedback(ctx context.Context, messageID string, req models.FeedbackReq) (models.FeedbackResp, error)
+	DeleteFeedback(ctx context.Context, messageID string, userID int64) error
+	UserData(ctx context.Context, userID int64) (models.UserData, error)
+	EraseUser(ctx context.Context, userID int64, anonymize bool) (models.EraseReport, error)
+	GetUsage(ctx context.Context, userID int64) (models.UsageResp, error)
+	ChatSettings(ctx context.Context, userID int64, chatUUID string) (models.ChatSettingsResp, error)
+	SetChatSettings(ctx context.Context, userID int64, chatUUID string, p models.GenerationParams) (models.ChatSettingsResp, error)
+	SwitchChatModel(ctx context.Context, userID int64, chatUUID string, modelID int64) (models.ChatModelResp, error)
+	SetArenaPreference(ctx context.Context, userID int64, messageUUID string) (models.ArenaPreferenceResp, error)
+	ListPromptTemplates(ctx context.Context, userID int64) (models.ListPromptTemplatesResp, error)
+	GetPromptTemplate(ctx context.Context, userID, templateID int64) (models.PromptTemplate, error)
+	CreatePromptTemplate(ctx context.Context, req models.PromptTemplateReq) (models.PromptTemplate, error)
+	UpdatePromptTemplate(ctx context.Context, templateID int64, req models.PromptTemplateReq) (models.PromptTemplate, error)
+	DeletePromptTemplate(ctx context.Context, userID, templateID int64) error
+	ListModels(ctx context.Context, activeOnly bool) ([]models.BotModel, error)
+	GetModel(ctx context.Context, modelID int64) (models.BotModel, error)
+	CreateModel(ctx context.Context, req models.CreateModelReq) (models.BotModel, error)
+	UpdateModel(ctx context.Context, modelID int64, req models.UpdateModelReq) (models.BotModel, error)
+	SetModelActive(ctx context.Context, modelID int64, active bool) (models.BotModel, error)
+	FeedbackStats(ctx context.Context, modelID int64, f models.FeedbackStatsFilter) (models.FeedbackStatsResp, error)
+	EachDatasetRecord(ctx context.Context, f models.DatasetFilter, fn func(models.DatasetRecord) error) error
+	Search(ctx context.Context, userID int64, query string, limit int) (models.SearchResp, error)
+}
+
+// Notifier доставляет события в открытые ws-сессии пользователя
+type Notifier interface {
+	NotifyUser(userID int64, v any)
 }
 
 type API struct {
-	log *slog.Logger
-	svc Storage
+	log        *slog.Logger
+	svc        Storage
+	notifier   Notifier
+	adminToken string
+
+	feedbackReasons  map[string]struct{}
+	maxCommentLength int
 }
 
-func NewAPI(log *slog.Logger, svc Storage) *API {
-	return &API{log: log, svc: svc}
+func NewAPI(log *slog.Logger, svc Storage, notifier Notifier, cfg *config.Config) *API {
+	reasons := make(map[string]struct{}, len(cfg.FEEDBACK.Reasons))
+	for _, r := range cfg.FEEDBACK.Reasons {
+		reasons[r] = struct{}{}
+	}
+
+	return &API{
+		log:              log,
+		svc:              svc,
+		notifier:         notifier,
+		adminToken:       cfg.ADMIN.Token,
+		feedbackReasons:  reasons,
+		maxCommentLength: cfg.FEEDBACK.MaxCommentLength,
+	}
 }
 
 type apiError struct {
@@ -68,7 +150,73 @@ func (a *API) Chats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// /chats/{chat_id}/messages or /chats/{chat_id}
+// /usage -> GET usage & quotas of user
+func (a *API) Usage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	a.usage(w, r)
+}
+
+// /search -> GET full-text search over user's chats
+func (a *API) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	a.search(w, r)
+}
+
+// /shared/{token} -> GET public snapshot, без user_id
+func (a *API) Shared(w http.ResponseWriter, r *http.Request) {
+	token := strings.Trim(strings.TrimPrefix(r.URL.Path, "/shared/"), "/")
+	if token == "" || strings.Contains(token, "/") {
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	a.sharedChat(w, r, token)
+}
+
+// /users/{user_id}/export?user_id=... — только свои данные
+func (a *API) UserByID(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/users/")
+	a.userData(w, r, strings.Split(strings.Trim(path, "/"), "/"), false)
+}
+
+// общий роутер для /users/... и /admin/users/...
+// Необратимое удаление (DELETE .../data) — только через admin.
+func (a *API) userData(w http.ResponseWriter, r *http.Request, parts []string, admin bool) {
+	if len(parts) != 2 {
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+
+	// пользователь выгружает только себя: user_id из query должен совпасть с путём
+	if !admin && parts[1] == "export" && r.URL.Query().Get("user_id") != parts[0] {
+		writeErr(w, http.StatusForbidden, "forbidden", "query user_id must match the path user_id")
+		return
+	}
+
+	switch {
+	case parts[1] == "export" && r.Method == http.MethodGet:
+		a.exportUserData(w, r, parts[0])
+	case parts[1] == "data" && r.Method == http.MethodDelete && !admin:
+		writeErr(w, http.StatusForbidden, "forbidden", "data erasure is available only via /admin/users/{user_id}/data")
+	case parts[1] == "data" && r.Method == http.MethodDelete:
+		a.eraseUserData(w, r, parts[0])
+	case parts[1] == "export" || parts[1] == "data":
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	default:
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+	}
+}
+
+// /chats/{chat_id}/messages, /chats/{chat_id}/export, /chats/{chat_id}/restore or /chats/{chat_id}
 func (a *API) ChatByID(w http.ResponseWriter, r *http.Request) {
 	// path: /chats/{id}/...
 	path := strings.TrimPrefix(r.URL.Path, "/chats/")
@@ -81,6 +229,16 @@ func (a *API) ChatByID(w http.ResponseWriter, r *http.Request) {
 
 	chatID := parts[0] // это chat_uuid
 
+	// /chats/import
+	if len(parts) == 1 && chatID == "import" {
+		if r.Method != http.MethodPost {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.importChats(w, r)
+		return
+	}
+
 	// /chats/{id}/messages
 	if len(parts) == 2 && parts[1] == "messages" {
 		if r.Method != http.MethodGet {
@@ -91,29 +249,168 @@ func (a *API) ChatByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// /chats/{id} (DELETE)
-	if len(parts) == 1 && r.Method == http.MethodDelete {
-		a.deleteChat(w, r, chatID)
+	// /chats/{id}/export?format=md|json|html
+	if len(parts) == 2 && parts[1] == "export" {
+		if r.Method != http.MethodGet {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.export(w, r, chatID)
+		return
+	}
+
+	// /chats/{id}/members (GET list, POST invite)
+	if len(parts) == 2 && parts[1] == "members" {
+		switch r.Method {
+		case http.MethodGet:
+			a.listMembers(w, r, chatID)
+		case http.MethodPost:
+			a.addMember(w, r, chatID)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
+		return
+	}
+
+	// /chats/{id}/members/{member_id} (DELETE)
+	if len(parts) == 3 && parts[1] == "members" {
+		if r.Method != http.MethodDelete {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.removeMember(w, r, chatID, parts[2])
+		return
+	}
+
+	// /chats/{id}/folder (PUT)
+	if len(parts) == 2 && parts[1] == "folder" {
+		if r.Method != http.MethodPut {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.setChatFolder(w, r, chatID)
+		return
+	}
+
+	// /chats/{id}/tags (POST)
+	if len(parts) == 2 && parts[1] == "tags" {
+		if r.Method != http.MethodPost {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.addChatTag(w, r, chatID)
+		return
+	}
+
+	// /chats/{id}/tags/{tag_id} (DELETE)
+	if len(parts) == 3 && parts[1] == "tags" {
+		if r.Method != http.MethodDelete {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.removeChatTag(w, r, chatID, parts[2])
+		return
+	}
+
+	// /chats/{id}/model (PUT)
+	if len(parts) == 2 && parts[1] == "model" {
+		if r.Method != http.MethodPut {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.switchChatModel(w, r, chatID)
+		return
+	}
+
+	// /chats/{id}/settings (GET, PUT)
+	if len(parts) == 2 && parts[1] == "settings" {
+		switch r.Method {
+		case http.MethodGet:
+			a.getChatSettings(w, r, chatID)
+		case http.MethodPut:
+			a.setChatSettings(w, r, chatID)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
+		return
+	}
+
+	// /chats/{id}/share (POST create, DELETE revoke)
+	if len(parts) == 2 && parts[1] == "share" {
+		switch r.Method {
+		case http.MethodPost:
+			a.createShare(w, r, chatID)
+		case http.MethodDelete:
+			a.revokeShares(w, r, chatID)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
+		return
+	}
+
+	// /chats/{id}/restore
+	if len(parts) == 2 && parts[1] == "restore" {
+		if r.Method != http.MethodPost {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.restoreChat(w, r, chatID)
+		return
+	}
+
+	// /chats/{id} (DELETE, PATCH)
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodDelete:
+			a.deleteChat(w, r, chatID)
+		case http.MethodPatch:
+			a.updateChat(w, r, chatID)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
 		return
 	}
 
 	writeErr(w, http.StatusNotFound, "not_found", "not found")
 }
 
-// /messages/{message_id}/feedback
+// /messages/{message_id}/feedback or /messages/{message_id}
 func (a *API) MessageByID(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/messages/")
 	parts := strings.Split(strings.Trim(path, "/"), "/")
 
-	if len(parts) != 2 || parts[1] != "feedback" {
-		writeErr(w, http.StatusNotFound, "not_found", "not found")
+	// /messages/{id} (DELETE)
+	if len(parts) == 1 && parts[0] != "" {
+		if r.Method != http.MethodDelete {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.deleteMessage(w, r, parts[0])
 		return
 	}
-	if r.Method != http.MethodPost {
-		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+
+	// /messages/{id}/preference (POST) — выбор ответа в arena
+	if len(parts) == 2 && parts[1] == "preference" {
+		if r.Method != http.MethodPost {
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		a.arenaPreference(w, r, parts[0])
+		return
+	}
+
+	if len(parts) != 2 || parts[1] != "feedback" {
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
 		return
 	}
 
 	messageID := parts[0] // это message_uuid
-	a.feedback(w, r, messageID)
+	switch r.Method {
+	case http.MethodPost:
+		a.feedback(w, r, messageID)
+	case http.MethodDelete:
+		a.deleteFeedback(w, r, messageID)
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	}
 }
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	models "MicroserviceWebsocket/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+func writeChatSettingsErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrChatNotFound:
+		writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+	case ErrForbidden:
+		writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+	default:
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+	}
+}
+
+// GET /chats/{chat_id}/settings?user_id=...
+func (a *API) getChatSettings(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := a.svc.ChatSettings(r.Context(), userID, chatID)
+	if err != nil {
+		writeChatSettingsErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PUT /chats/{chat_id}/settings
+func (a *API) setChatSettings(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	var req models.ChatSettingsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.UserID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id is required")
+		return
+	}
+
+	// context_size модели нужен для проверки max_tokens
+	cur, err := a.svc.ChatSettings(r.Context(), req.UserID, chatID)
+	if err != nil {
+		writeChatSettingsErr(w, err)
+		return
+	}
+	if msg := validateParams(req.Settings, cur.ContextSize); msg != "" {
+		writeErr(w, http.StatusBadRequest, "validation_error", msg)
+		return
+	}
+
+	resp, err := a.svc.SetChatSettings(r.Context(), req.UserID, chatID, req.Settings)
+	if err != nil {
+		writeChatSettingsErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PUT /chats/{chat_id}/model
+func (a *API) switchChatModel(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	var req models.ChatModelReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.UserID <= 0 || req.ModelID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id and model_id are required")
+		return
+	}
+
+	resp, err := a.svc.SwitchChatModel(r.Context(), req.UserID, chatID, req.ModelID)
+	if err != nil {
+		switch err {
+		case ErrModelNotFound:
+			writeErr(w, http.StatusNotFound, "model_not_found", "model not found or inactive")
+		default:
+			writeChatSettingsErr(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,224 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/logger/sl"
+)
+
+// streamWriteTimeout — дедлайн записи для выгрузок; общий WriteTimeout сервера
+// (websocket.timeout, секунды) обрывает длинные стримы
+const streamWriteTimeout = 10 * time.Minute
+
+// extendWriteDeadline продлевает дедлайн записи перед стримингом ответа
+func (a *API) extendWriteDeadline(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		a.log.Warn("extend write deadline failed", sl.Err(err))
+	}
+}
+
+// chatExporter пишет чат в w по одному сообщению, не собирая всё в память
+type chatExporter interface {
+	contentType() string
+	ext() string
+	begin(w io.Writer, info models.ChatInfo) error
+	message(w io.Writer, it models.MessageItem) error
+	end(w io.Writer) error
+}
+
+func exporterFor(format string) (chatExporter, bool) {
+	switch format {
+	case "", "md":
+		return &mdExporter{}, true
+	case "json":
+		return &jsonExporter{}, true
+	case "html":
+		return &htmlExporter{}, true
+	default:
+		return nil, false
+	}
+}
+
+var unsafeFilename = regexp.MustCompile(`[^\p{L}\p{N}_-]+`)
+
+// contentDisposition: имя файла из заголовка чата, не-ASCII кодируется по RFC 2231
+func contentDisposition(info models.ChatInfo, ext string) string {
+	name := strings.Trim(unsafeFilename.ReplaceAllString(info.Title, "_"), "_")
+	if name == "" {
+		name = "chat-" + info.ID
+	}
+	return mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + ext})
+}
+
+func roleLabel(role string) string {
+	if role == "bot" {
+		return "Bot"
+	}
+	return "User"
+}
+
+func feedbackLabel(fb *bool) string {
+	switch {
+	case fb == nil:
+		return ""
+	case *fb:
+		return "positive"
+	default:
+		return "negative"
+	}
+}
+
+// ---------- markdown ----------
+
+type mdExporter struct{}
+
+func (mdExporter) contentType() string { return "text/markdown; charset=utf-8" }
+func (mdExporter) ext() string         { return "md" }
+
+func (mdExporter) begin(w io.Writer, info models.ChatInfo) error {
+	_, err := fmt.Fprintf(w, "# %s\n\n- Model: %s %s\n- Created: %s\n- Exported: %s\n\n---\n",
+		info.Title, info.ModelName, info.ModelVersion, info.CreatedAt, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func (mdExporter) message(w io.Writer, it models.MessageItem) error {
+	header := fmt.Sprintf("**%s** · %s", roleLabel(it.Role), it.CreatedAt)
+	if fb := feedbackLabel(it.Feedback); fb != "" {
+		header += " · feedback: " + fb
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n\n%s\n", header, it.Content)
+	return err
+}
+
+func (mdExporter) end(io.Writer) error { return nil }
+
+// ---------- json ----------
+
+type jsonExporter struct {
+	n int
+}
+
+func (jsonExporter) contentType() string { return "application/json" }
+func (jsonExporter) ext() string         { return "json" }
+
+func (e *jsonExporter) begin(w io.Writer, info models.ChatInfo) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, `{"chat":%s,"messages":[`, b)
+	return err
+}
+
+func (e *jsonExporter) message(w io.Writer, it models.MessageItem) error {
+	b, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	if e.n > 0 {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	e.n++
+	_, err = w.Write(b)
+	return err
+}
+
+func (e *jsonExporter) end(w io.Writer) error {
+	_, err := io.WriteString(w, "]}\n")
+	return err
+}
+
+// ---------- html ----------
+
+type htmlExporter struct{}
+
+func (htmlExporter) contentType() string { return "text/html; charset=utf-8" }
+func (htmlExporter) ext() string         { return "html" }
+
+func (htmlExporter) begin(w io.Writer, info models.ChatInfo) error {
+	title := html.EscapeString(info.Title)
+	_, err := fmt.Fprintf(w, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>%s</title>
+<style>
+body{font-family:sans-serif;max-width:800px;margin:2em auto;line-height:1.5}
+.msg{border-radius:8px;padding:.5em 1em;margin:1em 0;white-space:pre-wrap}
+.user{background:#eef}.bot{background:#f4f4f4}
+.meta{color:#666;font-size:.85em}
+</style></head><body>
+<h1>%s</h1>
+<p class="meta">Model: %s %s · Created: %s</p>
+`, title, title, html.EscapeString(info.ModelName), html.EscapeString(info.ModelVersion), info.CreatedAt)
+	return err
+}
+
+func (htmlExporter) message(w io.Writer, it models.MessageItem) error {
+	meta := roleLabel(it.Role) + " · " + it.CreatedAt
+	if fb := feedbackLabel(it.Feedback); fb != "" {
+		meta += " · feedback: " + fb
+	}
+	_, err := fmt.Fprintf(w, "<div class=\"msg %s\"><div class=\"meta\">%s</div>%s</div>\n",
+		html.EscapeString(it.Role), html.EscapeString(meta), html.EscapeString(it.Content))
+	return err
+}
+
+func (htmlExporter) end(w io.Writer) error {
+	_, err := io.WriteString(w, "</body></html>\n")
+	return err
+}
+
+// exportChat стримит чат: заголовки ответа пишутся только после проверки доступа
+func (a *API) exportChat(w http.ResponseWriter, r *http.Request, chatID string, userID int64, exp chatExporter) {
+	info, err := a.svc.ChatInfo(r.Context(), userID, chatID)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	a.extendWriteDeadline(w)
+	w.Header().Set("Content-Type", exp.contentType())
+	w.Header().Set("Content-Disposition", contentDisposition(info, exp.ext()))
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	err = exp.begin(w, info)
+	if err == nil {
+		err = a.svc.EachMessage(r.Context(), userID, chatID, func(it models.MessageItem) error {
+			if err := exp.message(w, it); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = exp.end(w)
+	}
+	if err != nil {
+		// статус уже отправлен — остаётся только лог
+		a.log.Error("chat export failed", slog.String("chat_id", chatID), sl.Err(err))
+	}
+}
@@ -2,12 +2,18 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"golang.org/x/exp/slog"
 
 	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/logger/sl"
+	"MicroserviceWebsocket/internal/services/userdata"
 )
 
 func (a *API) createChat(w http.ResponseWriter, r *http.Request) {
@@ -55,15 +61,86 @@ func (a *API) listChats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := a.svc.ListChats(r.Context(), userID)
+	var filter models.ChatsFilter
+	if s := r.URL.Query().Get("archived"); s != "" {
+		filter.Archived, err = strconv.ParseBool(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "archived must be true or false")
+			return
+		}
+	}
+	if s := r.URL.Query().Get("pinned"); s != "" {
+		pinned, err := strconv.ParseBool(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "pinned must be true or false")
+			return
+		}
+		filter.Pinned = &pinned
+	}
+	if s := r.URL.Query().Get("deleted"); s != "" {
+		filter.Deleted, err = strconv.ParseBool(s)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "validation_error", "deleted must be true or false")
+			return
+		}
+	}
+	if s := r.URL.Query().Get("folder_id"); s != "" {
+		folderID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || folderID <= 0 {
+			writeErr(w, http.StatusBadRequest, "validation_error", "folder_id must be int64")
+			return
+		}
+		filter.FolderID = &folderID
+	}
+	if s := r.URL.Query().Get("tag_id"); s != "" {
+		tagID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || tagID <= 0 {
+			writeErr(w, http.StatusBadRequest, "validation_error", "tag_id must be int64")
+			return
+		}
+		filter.TagID = &tagID
+	}
+
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := a.svc.ListChats(r.Context(), userID, filter, page)
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		switch err {
+		case ErrBadCursor:
+			writeErr(w, http.StatusBadRequest, "validation_error", "invalid cursor")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
 		return
 	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// parsePage читает limit/before/after; при ошибке сам пишет ответ
+func parsePage(w http.ResponseWriter, r *http.Request) (models.PageReq, bool) {
+	q := r.URL.Query()
+	page := models.PageReq{Before: q.Get("before"), After: q.Get("after")}
+
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			writeErr(w, http.StatusBadRequest, "validation_error", "limit must be a positive int")
+			return models.PageReq{}, false
+		}
+		page.Limit = limit
+	}
+	if page.Before != "" && page.After != "" {
+		writeErr(w, http.StatusBadRequest, "validation_error", "use either before or after, not both")
+		return models.PageReq{}, false
+	}
+
+	return page, true
+}
+
 func (a *API) listMessages(w http.ResponseWriter, r *http.Request, chatID string) {
 	// chatID сейчас UUID строкой
 	if _, err := uuid.Parse(chatID); err != nil {
@@ -78,13 +155,20 @@ func (a *API) listMessages(w http.ResponseWriter, r *http.Request, chatID string
 		return
 	}
 
-	resp, err := a.svc.ListMessages(r.Context(), userID, chatID)
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := a.svc.ListMessages(r.Context(), userID, chatID, page)
 	if err != nil {
 		switch err {
+		case ErrBadCursor:
+			writeErr(w, http.StatusBadRequest, "validation_error", "invalid cursor")
 		case ErrChatNotFound:
 			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
 		case ErrForbidden:
-			writeErr(w, http.StatusForbidden, "forbidden", "chat does not belong to user")
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
 		default:
 			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
 		}
@@ -114,7 +198,241 @@ func (a *API) deleteChat(w http.ResponseWriter, r *http.Request, chatID string)
 		case ErrChatNotFound:
 			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
 		case ErrForbidden:
-			writeErr(w, http.StatusForbidden, "forbidden", "chat does not belong to user")
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) export(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	exp, ok := exporterFor(r.URL.Query().Get("format"))
+	if !ok {
+		writeErr(w, http.StatusBadRequest, "validation_error", "format must be one of: md, json, html")
+		return
+	}
+
+	a.exportChat(w, r, chatID, userID, exp)
+}
+
+func (a *API) listMembers(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := a.svc.ListMembers(r.Context(), userID, chatID)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) addMember(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	var req models.AddMemberReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.UserID <= 0 || req.MemberID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id and member_id are required")
+		return
+	}
+	if req.Role != models.RoleEditor && req.Role != models.RoleViewer {
+		writeErr(w, http.StatusBadRequest, "validation_error", "role must be editor or viewer")
+		return
+	}
+
+	resp, err := a.svc.AddMember(r.Context(), req, chatID)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "only the owner can invite, and the owner's role cannot be changed")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) removeMember(w http.ResponseWriter, r *http.Request, chatID, memberIDStr string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+	memberID, err := strconv.ParseInt(memberIDStr, 10, 64)
+	if err != nil || memberID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "member_id must be int64")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	err = a.svc.RemoveMember(r.Context(), userID, chatID, memberID)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrMemberNotFound:
+			writeErr(w, http.StatusNotFound, "member_not_found", "member not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) createShare(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := a.svc.CreateShare(r.Context(), userID, chatID)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (a *API) revokeShares(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	err = a.svc.RevokeShares(r.Context(), userID, chatID)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) sharedChat(w http.ResponseWriter, r *http.Request, token string) {
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := a.svc.SharedChat(r.Context(), token, page)
+	if err != nil {
+		switch err {
+		case ErrShareNotFound:
+			writeErr(w, http.StatusNotFound, "share_not_found", "share link not found or revoked")
+		case ErrBadCursor:
+			writeErr(w, http.StatusBadRequest, "validation_error", "invalid cursor")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) restoreChat(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	err = a.svc.RestoreChat(r.Context(), userID, chatID)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		case ErrNotDeleted:
+			writeErr(w, http.StatusConflict, "chat_not_deleted", "chat is not in trash")
 		default:
 			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
 		}
@@ -124,6 +442,96 @@ func (a *API) deleteChat(w http.ResponseWriter, r *http.Request, chatID string)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+const maxTitleLen = 80
+
+func (a *API) updateChat(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	var req models.UpdateChatReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.UserID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id is required")
+		return
+	}
+	if req.Title == nil && req.Pinned == nil && req.Archived == nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "nothing to update: title, pinned or archived required")
+		return
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" || utf8.RuneCountInString(title) > maxTitleLen {
+			writeErr(w, http.StatusBadRequest, "validation_error", "title must be 1..80 characters")
+			return
+		}
+		req.Title = &title
+	}
+
+	resp, err := a.svc.UpdateChat(r.Context(), chatID, req)
+	if err != nil {
+		switch err {
+		case ErrChatNotFound:
+			writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) deleteMessage(w http.ResponseWriter, r *http.Request, messageID string) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "message_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := a.svc.DeleteMessage(r.Context(), messageID, userID)
+	if err != nil {
+		switch err {
+		case ErrMessageNotFound:
+			writeErr(w, http.StatusNotFound, "message_not_found", "message not found")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "only the author or the chat owner can delete this message")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	// открытые сессии всех участников чата уберут сообщения у себя
+	event := models.WSMessagesDeleted{
+		Type:         "messages_deleted",
+		ChatUUID:     resp.ChatID,
+		MessageUUIDs: resp.DeletedIDs,
+	}
+	members, err := a.svc.ChatMemberIDs(r.Context(), resp.ChatID)
+	if err != nil {
+		a.log.Error("list chat members for notify failed", slog.String("chat_uuid", resp.ChatID), sl.Err(err))
+		members = []int64{userID}
+	}
+	for _, id := range members {
+		a.notifier.NotifyUser(id, event)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
 func (a *API) feedback(w http.ResponseWriter, r *http.Request, messageID string) {
 	// messageID сейчас UUID строкой
 	if _, err := uuid.Parse(messageID); err != nil {
@@ -140,21 +548,210 @@ func (a *API) feedback(w http.ResponseWriter, r *http.Request, messageID string)
 		writeErr(w, http.StatusBadRequest, "validation_error", "user_id is required")
 		return
 	}
+	if req.Reason != "" {
+		if _, ok := a.feedbackReasons[req.Reason]; !ok {
+			writeErr(w, http.StatusBadRequest, "validation_error", "unknown reason")
+			return
+		}
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if a.maxCommentLength > 0 && utf8.RuneCountInString(req.Comment) > a.maxCommentLength {
+		writeErr(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("comment must be at most %d characters", a.maxCommentLength))
+		return
+	}
+
+	resp, err := a.svc.SetFeedback(r.Context(), messageID, req)
+	if err != nil {
+		switch err {
+		case ErrMessageNotFound:
+			writeErr(w, http.StatusNotFound, "message_not_found", "message not found")
+		case ErrNotBotMessage:
+			writeErr(w, http.StatusBadRequest, "not_bot_message", "feedback allowed only for bot messages")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /messages/{message_id}/preference — этот ответ лучше остальных ответов arena
+func (a *API) arenaPreference(w http.ResponseWriter, r *http.Request, messageID string) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "message_id must be a valid uuid")
+		return
+	}
+
+	var req models.ArenaPreferenceReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.UserID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id is required")
+		return
+	}
 
-	resp, err := a.svc.SetFeedback(r.Context(), messageID, req.UserID, req.IsPositive)
+	resp, err := a.svc.SetArenaPreference(r.Context(), req.UserID, messageID)
 	if err != nil {
 		switch err {
 		case ErrMessageNotFound:
 			writeErr(w, http.StatusNotFound, "message_not_found", "message not found")
+		case ErrNotBotMessage:
+			writeErr(w, http.StatusBadRequest, "not_bot_message", "preference allowed only for bot messages")
+		case ErrNotArenaMessage:
+			writeErr(w, http.StatusBadRequest, "not_arena_message", "message has no answers from other models to compare with")
+		case ErrForbidden:
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) deleteFeedback(w http.ResponseWriter, r *http.Request, messageID string) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "message_id must be a valid uuid")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	err = a.svc.DeleteFeedback(r.Context(), messageID, userID)
+	if err != nil {
+		switch err {
+		case ErrMessageNotFound:
+			writeErr(w, http.StatusNotFound, "message_not_found", "message not found")
+		case ErrFeedbackNotFound:
+			writeErr(w, http.StatusNotFound, "feedback_not_found", "feedback not found")
 		case ErrNotBotMessage:
 			writeErr(w, http.StatusBadRequest, "not_bot_message", "feedback allowed only for bot messages")
 		case ErrForbidden:
-			writeErr(w, http.StatusForbidden, "forbidden", "message does not belong to user")
+			writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
 		default:
 			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
 		}
 		return
 	}
 
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) usage(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := a.svc.GetUsage(r.Context(), userID)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
 	writeJSON(w, http.StatusOK, resp)
 }
+
+const maxSearchQueryLen = 200
+
+func (a *API) search(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" || utf8.RuneCountInString(q) > maxSearchQueryLen {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query q is required and must be at most 200 characters")
+		return
+	}
+
+	var limit int
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			writeErr(w, http.StatusBadRequest, "validation_error", "limit must be a positive int")
+			return
+		}
+	}
+
+	resp, err := a.svc.Search(r.Context(), userID, q, limit)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) exportUserData(w http.ResponseWriter, r *http.Request, userIDStr string) {
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id must be int64")
+		return
+	}
+
+	data, err := a.svc.UserData(r.Context(), userID)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
+	a.extendWriteDeadline(w)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+userdata.FileName(userID)+`"`)
+	w.WriteHeader(http.StatusOK)
+	if err := userdata.WriteArchive(w, data); err != nil {
+		a.log.Error("user data export failed", slog.Int64("user_id", userID), sl.Err(err))
+	}
+}
+
+// DELETE .../data?mode=delete|anonymize&confirm=true
+func (a *API) eraseUserData(w http.ResponseWriter, r *http.Request, userIDStr string) {
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id must be int64")
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode != "delete" && mode != "anonymize" {
+		writeErr(w, http.StatusBadRequest, "validation_error", "mode must be one of: delete, anonymize")
+		return
+	}
+	// операция необратима — требуем явного подтверждения
+	if r.URL.Query().Get("confirm") != "true" {
+		writeErr(w, http.StatusBadRequest, "validation_error", "confirm=true is required")
+		return
+	}
+
+	report, err := a.svc.EraseUser(r.Context(), userID, mode == "anonymize")
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
+	a.log.Info("user data erased",
+		slog.Int64("user_id", userID),
+		slog.String("mode", report.Mode),
+		slog.Int64("chats", report.Chats),
+		slog.Int64("transferred_chats", report.TransferredChats),
+		slog.Int64("messages", report.Messages),
+	)
+	writeJSON(w, http.StatusOK, report)
+}
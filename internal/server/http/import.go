@@ -0,0 +1,273 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	models "MicroserviceWebsocket/internal/domain"
+)
+
+const maxImportBody = 32 << 20 // 32MB
+
+func (a *API) importChats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	userID, err := strconv.ParseInt(q.Get("user_id"), 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBody))
+	if err != nil {
+		writeErr(w, http.StatusRequestEntityTooLarge, "too_large", "import document is too large")
+		return
+	}
+
+	var req models.ImportReq
+	switch q.Get("format") {
+	case "", "native":
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+			return
+		}
+	case "chatgpt":
+		req.Chats, err = fromChatGPT(body)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "bad_json", err.Error())
+			return
+		}
+	default:
+		writeErr(w, http.StatusBadRequest, "validation_error", "format must be one of: native, chatgpt")
+		return
+	}
+
+	// модель по умолчанию: из тела (native) или из query
+	if req.ModelName == "" {
+		req.ModelName, req.ModelVersion = q.Get("model_name"), q.Get("model_version")
+	}
+	if len(req.Chats) == 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chats must not be empty")
+		return
+	}
+
+	resp := models.ImportResp{Imported: make([]string, 0, len(req.Chats)), Failed: make([]models.ImportFailure, 0)}
+	for i := range req.Chats {
+		chat := &req.Chats[i]
+		if chat.ModelName == "" {
+			chat.ModelName, chat.ModelVersion = req.ModelName, req.ModelVersion
+		}
+
+		if err := normalizeImportChat(chat); err != nil {
+			resp.Failed = append(resp.Failed, models.ImportFailure{Index: i, ChatUUID: chat.ChatUUID, Error: err.Error()})
+			continue
+		}
+
+		if err := a.svc.ImportChat(r.Context(), userID, *chat); err != nil {
+			msg := "internal error"
+			switch err {
+			case ErrModelNotFound:
+				msg = "model not found"
+			case ErrChatExists:
+				msg = "chat already exists"
+			case ErrMessageExists:
+				msg = "message already exists"
+			}
+			resp.Failed = append(resp.Failed, models.ImportFailure{Index: i, ChatUUID: chat.ChatUUID, Error: msg})
+			continue
+		}
+		resp.Imported = append(resp.Imported, chat.ChatUUID)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// normalizeImportChat проверяет uuid/роли/ссылки и упорядочивает сообщения по времени,
+// так чтобы reply_to всегда указывал на уже вставленное сообщение
+func normalizeImportChat(chat *models.ImportChat) error {
+	if _, err := uuid.Parse(chat.ChatUUID); err != nil {
+		return errors.New("chat_uuid must be a valid uuid")
+	}
+	if chat.ModelName == "" || chat.ModelVersion == "" {
+		return errors.New("model_name and model_version are required")
+	}
+	chat.Title = strings.TrimSpace(chat.Title)
+	if len([]rune(chat.Title)) > maxTitleLen {
+		chat.Title = string([]rune(chat.Title)[:maxTitleLen])
+	}
+
+	// сообщения без времени идут следом за предыдущим
+	base := chat.CreatedAt
+	if base.IsZero() {
+		base = time.Now().UTC()
+	}
+	prev := base
+	for i := range chat.Messages {
+		m := &chat.Messages[i]
+		if m.CreatedAt.IsZero() {
+			m.CreatedAt = prev.Add(time.Millisecond)
+		}
+		prev = m.CreatedAt
+	}
+	sort.SliceStable(chat.Messages, func(i, j int) bool {
+		return chat.Messages[i].CreatedAt.Before(chat.Messages[j].CreatedAt)
+	})
+	if chat.CreatedAt.IsZero() {
+		chat.CreatedAt = base
+		if len(chat.Messages) > 0 && chat.Messages[0].CreatedAt.Before(base) {
+			chat.CreatedAt = chat.Messages[0].CreatedAt
+		}
+	}
+
+	seen := make(map[string]bool, len(chat.Messages))
+	for i, m := range chat.Messages {
+		if _, err := uuid.Parse(m.MessageUUID); err != nil {
+			return fmt.Errorf("messages[%d]: message_uuid must be a valid uuid", i)
+		}
+		if seen[m.MessageUUID] {
+			return fmt.Errorf("messages[%d]: duplicate message_uuid %s", i, m.MessageUUID)
+		}
+		// как CHECK (role IN ('user', 'bot')) в таблице messages
+		if m.Role != "user" && m.Role != "bot" {
+			return fmt.Errorf("messages[%d]: role must be user or bot", i)
+		}
+		if strings.TrimSpace(m.Content) == "" {
+			return fmt.Errorf("messages[%d]: content must not be empty", i)
+		}
+		if m.ReplyToMessageID != "" && !seen[m.ReplyToMessageID] {
+			return fmt.Errorf("messages[%d]: reply_to_message_id must reference an earlier message of the same chat", i)
+		}
+		seen[m.MessageUUID] = true
+	}
+
+	return nil
+}
+
+// ---------- ChatGPT export (conversations.json) ----------
+
+type chatgptConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	CurrentNode    string                 `json:"current_node"`
+	Mapping        map[string]chatgptNode `json:"mapping"`
+}
+
+type chatgptNode struct {
+	ID      string          `json:"id"`
+	Parent  string          `json:"parent"`
+	Message *chatgptMessage `json:"message"`
+}
+
+type chatgptMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	Content struct {
+		Parts []any `json:"parts"`
+	} `json:"content"`
+	CreateTime float64 `json:"create_time"`
+}
+
+func unixToTime(sec float64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(sec)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+// fromChatGPT берёт из каждого разговора основную ветку (от current_node к корню);
+// system/tool сообщения пропускаются, assistant -> bot
+func fromChatGPT(data []byte) ([]models.ImportChat, error) {
+	var convs []chatgptConversation
+	if err := json.Unmarshal(data, &convs); err != nil {
+		return nil, errors.New("invalid chatgpt export: expected conversations.json array")
+	}
+
+	chats := make([]models.ImportChat, 0, len(convs))
+	for _, c := range convs {
+		chat := models.ImportChat{
+			ChatUUID:  c.ConversationID,
+			Title:     c.Title,
+			CreatedAt: unixToTime(c.CreateTime),
+		}
+		if chat.ChatUUID == "" {
+			chat.ChatUUID = c.ID
+		}
+
+		// ветка от листа к корню; защита от циклов
+		var branch []chatgptNode
+		visited := make(map[string]bool)
+		for id := c.CurrentNode; id != "" && !visited[id]; {
+			visited[id] = true
+			node, ok := c.Mapping[id]
+			if !ok {
+				break
+			}
+			branch = append(branch, node)
+			id = node.Parent
+		}
+
+		lastUser := ""
+		for i := len(branch) - 1; i >= 0; i-- {
+			msg := branch[i].Message
+			if msg == nil {
+				continue
+			}
+
+			var role string
+			switch msg.Author.Role {
+			case "user":
+				role = "user"
+			case "assistant":
+				role = "bot"
+			default:
+				continue
+			}
+
+			parts := make([]string, 0, len(msg.Content.Parts))
+			for _, p := range msg.Content.Parts {
+				if s, ok := p.(string); ok && s != "" {
+					parts = append(parts, s)
+				}
+			}
+			content := strings.Join(parts, "\n")
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+
+			id := msg.ID
+			if id == "" {
+				id = branch[i].ID
+			}
+			m := models.ImportMessage{
+				MessageUUID: id,
+				Role:        role,
+				Content:     content,
+				CreatedAt:   unixToTime(msg.CreateTime),
+			}
+			if role == "bot" {
+				m.ReplyToMessageID = lastUser
+			} else {
+				lastUser = id
+			}
+			chat.Messages = append(chat.Messages, m)
+		}
+
+		chats = append(chats, chat)
+	}
+
+	return chats, nil
+}
@@ -0,0 +1,271 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	models "MicroserviceWebsocket/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const maxLabelLen = 64
+
+// labelOps — папки и теги обслуживаются одним набором хендлеров
+type labelOps struct {
+	kind     string // folder|tag — для кодов ошибок
+	notFound error
+	list     func(ctx context.Context, userID int64) (models.ListLabelsResp, error)
+	create   func(ctx context.Context, userID int64, name string) (models.Label, error)
+	rename   func(ctx context.Context, userID, id int64, name string) (models.Label, error)
+	delete   func(ctx context.Context, userID, id int64) error
+}
+
+// /folders (GET, POST), /folders/{id} (PATCH, DELETE)
+func (a *API) Folders(w http.ResponseWriter, r *http.Request) {
+	a.labels(w, r, "/folders", labelOps{
+		kind:     "folder",
+		notFound: ErrFolderNotFound,
+		list:     a.svc.ListFolders,
+		create:   a.svc.CreateFolder,
+		rename:   a.svc.RenameFolder,
+		delete:   a.svc.DeleteFolder,
+	})
+}
+
+// /tags (GET, POST), /tags/{id} (PATCH, DELETE)
+func (a *API) Tags(w http.ResponseWriter, r *http.Request) {
+	a.labels(w, r, "/tags", labelOps{
+		kind:     "tag",
+		notFound: ErrTagNotFound,
+		list:     a.svc.ListTags,
+		create:   a.svc.CreateTag,
+		rename:   a.svc.RenameTag,
+		delete:   a.svc.DeleteTag,
+	})
+}
+
+func (a *API) labels(w http.ResponseWriter, r *http.Request, prefix string, ops labelOps) {
+	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	if idStr == "" {
+		switch r.Method {
+		case http.MethodGet:
+			a.listLabels(w, r, ops)
+		case http.MethodPost:
+			a.createLabel(w, r, ops)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		a.renameLabel(w, r, ops, id)
+	case http.MethodDelete:
+		a.deleteLabel(w, r, ops, id)
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	}
+}
+
+func (a *API) listLabels(w http.ResponseWriter, r *http.Request, ops labelOps) {
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := ops.list(r.Context(), userID)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decodeLabelReq читает {user_id, name} и нормализует имя
+func decodeLabelReq(w http.ResponseWriter, r *http.Request) (models.LabelReq, bool) {
+	var req models.LabelReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return req, false
+	}
+	if req.UserID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id is required")
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxLabelLen {
+		writeErr(w, http.StatusBadRequest, "validation_error", "name must be 1..64 characters")
+		return req, false
+	}
+	return req, true
+}
+
+func (a *API) createLabel(w http.ResponseWriter, r *http.Request, ops labelOps) {
+	req, ok := decodeLabelReq(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := ops.create(r.Context(), req.UserID, req.Name)
+	if err != nil {
+		switch err {
+		case ErrNameTaken:
+			writeErr(w, http.StatusConflict, "name_taken", ops.kind+" with this name already exists")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (a *API) renameLabel(w http.ResponseWriter, r *http.Request, ops labelOps, id int64) {
+	req, ok := decodeLabelReq(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := ops.rename(r.Context(), req.UserID, id, req.Name)
+	if err != nil {
+		switch err {
+		case ops.notFound:
+			writeErr(w, http.StatusNotFound, ops.kind+"_not_found", ops.kind+" not found")
+		case ErrNameTaken:
+			writeErr(w, http.StatusConflict, "name_taken", ops.kind+" with this name already exists")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) deleteLabel(w http.ResponseWriter, r *http.Request, ops labelOps, id int64) {
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	err = ops.delete(r.Context(), userID, id)
+	if err != nil {
+		switch err {
+		case ops.notFound:
+			writeErr(w, http.StatusNotFound, ops.kind+"_not_found", ops.kind+" not found")
+		default:
+			writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeChatLabelErr — ошибки привязки чата к папке/тегу
+func writeChatLabelErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrChatNotFound:
+		writeErr(w, http.StatusNotFound, "chat_not_found", "chat not found")
+	case ErrForbidden:
+		writeErr(w, http.StatusForbidden, "forbidden", "not enough permissions for this chat")
+	case ErrFolderNotFound:
+		writeErr(w, http.StatusNotFound, "folder_not_found", "folder not found")
+	case ErrTagNotFound:
+		writeErr(w, http.StatusNotFound, "tag_not_found", "tag not found")
+	default:
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+	}
+}
+
+func (a *API) setChatFolder(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	var req models.ChatFolderReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.UserID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id is required")
+		return
+	}
+
+	if err := a.svc.SetChatFolder(r.Context(), req.UserID, chatID, req.FolderID); err != nil {
+		writeChatLabelErr(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) addChatTag(w http.ResponseWriter, r *http.Request, chatID string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+
+	var req models.ChatTagReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.UserID <= 0 || req.TagID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id and tag_id are required")
+		return
+	}
+
+	if err := a.svc.AddChatTag(r.Context(), req.UserID, chatID, req.TagID); err != nil {
+		writeChatLabelErr(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) removeChatTag(w http.ResponseWriter, r *http.Request, chatID, tagIDStr string) {
+	if _, err := uuid.Parse(chatID); err != nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "chat_id must be a valid uuid")
+		return
+	}
+	tagID, err := strconv.ParseInt(tagIDStr, 10, 64)
+	if err != nil || tagID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "tag_id must be int64")
+		return
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	if err := a.svc.RemoveChatTag(r.Context(), userID, chatID, tagID); err != nil {
+		writeChatLabelErr(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
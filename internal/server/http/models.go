@@ -0,0 +1,211 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	models "MicroserviceWebsocket/internal/domain"
+)
+
+const (
+	maxSystemPromptLen = 4000
+	maxModelFieldLen   = 100
+)
+
+// validateParams проверяет диапазоны; пустое сообщение = всё ок.
+// contextSize > 0 ограничивает max_tokens.
+func validateParams(p models.GenerationParams, contextSize int) string {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return "temperature must be 0..2"
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return "top_p must be in (0, 1]"
+	}
+	if p.MaxTokens != nil {
+		if *p.MaxTokens <= 0 {
+			return "max_tokens must be positive"
+		}
+		if contextSize > 0 && *p.MaxTokens > contextSize {
+			return "max_tokens must not exceed context_size"
+		}
+	}
+	if p.SystemPrompt != nil && len([]rune(*p.SystemPrompt)) > maxSystemPromptLen {
+		return "system_prompt is too long"
+	}
+	return ""
+}
+
+// validEndpoint — ws(s)-адрес gateway модели; пустой = общий gateway из конфига
+func validEndpoint(s string) bool {
+	if s == "" {
+		return true
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "ws" || u.Scheme == "wss") && u.Host != ""
+}
+
+// GET /models — активные модели для выбора на фронте (без admin-полей)
+func (a *API) Models(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+
+	items, err := a.svc.ListModels(r.Context(), true)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
+	resp := models.ListPublicModelsResp{Items: make([]models.PublicModel, 0, len(items))}
+	for _, m := range items {
+		resp.Items = append(resp.Items, models.PublicModel{
+			ID:            m.ID,
+			Name:          m.Name,
+			Version:       m.Version,
+			ContextSize:   m.ContextSize,
+			DefaultParams: m.DefaultParams,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) listModels(w http.ResponseWriter, r *http.Request) {
+	items, err := a.svc.ListModels(r.Context(), false)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.ListModelsResp{Items: items})
+}
+
+func (a *API) getModel(w http.ResponseWriter, r *http.Request, modelID int64) {
+	resp, err := a.svc.GetModel(r.Context(), modelID)
+	if err != nil {
+		writeModelErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) createModel(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateModelReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.Version = strings.TrimSpace(req.Version)
+	req.Endpoint = strings.TrimSpace(req.Endpoint)
+	if req.Name == "" || req.Version == "" || len(req.Name) > maxModelFieldLen || len(req.Version) > maxModelFieldLen {
+		writeErr(w, http.StatusBadRequest, "validation_error", "name and version are required (max 100 characters)")
+		return
+	}
+	if !validEndpoint(req.Endpoint) {
+		writeErr(w, http.StatusBadRequest, "validation_error", "endpoint must be a ws:// or wss:// url")
+		return
+	}
+	if req.ContextSize < 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "context_size must not be negative")
+		return
+	}
+	if (req.DailyTokenQuota != nil && *req.DailyTokenQuota < 0) || (req.MonthlyTokenQuota != nil && *req.MonthlyTokenQuota < 0) {
+		writeErr(w, http.StatusBadRequest, "validation_error", "quotas must not be negative")
+		return
+	}
+	if msg := validateParams(req.DefaultParams, req.ContextSize); msg != "" {
+		writeErr(w, http.StatusBadRequest, "validation_error", msg)
+		return
+	}
+
+	resp, err := a.svc.CreateModel(r.Context(), req)
+	if err != nil {
+		writeModelErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (a *API) updateModel(w http.ResponseWriter, r *http.Request, modelID int64) {
+	var req models.UpdateModelReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return
+	}
+	if req.Endpoint == nil && req.ContextSize == nil && req.DefaultParams == nil &&
+		req.DailyTokenQuota == nil && req.MonthlyTokenQuota == nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "nothing to update")
+		return
+	}
+	if req.Endpoint != nil {
+		endpoint := strings.TrimSpace(*req.Endpoint)
+		if !validEndpoint(endpoint) {
+			writeErr(w, http.StatusBadRequest, "validation_error", "endpoint must be a ws:// or wss:// url")
+			return
+		}
+		req.Endpoint = &endpoint
+	}
+	if req.ContextSize != nil && *req.ContextSize < 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "context_size must not be negative")
+		return
+	}
+	if (req.DailyTokenQuota != nil && *req.DailyTokenQuota < 0) || (req.MonthlyTokenQuota != nil && *req.MonthlyTokenQuota < 0) {
+		writeErr(w, http.StatusBadRequest, "validation_error", "quotas must not be negative")
+		return
+	}
+	if req.DefaultParams != nil || req.ContextSize != nil {
+		// max_tokens сверяем с итоговым context_size
+		cur, err := a.svc.GetModel(r.Context(), modelID)
+		if err != nil {
+			writeModelErr(w, err)
+			return
+		}
+		params, contextSize := cur.DefaultParams, cur.ContextSize
+		if req.DefaultParams != nil {
+			params = *req.DefaultParams
+		}
+		if req.ContextSize != nil {
+			contextSize = *req.ContextSize
+		}
+		if msg := validateParams(params, contextSize); msg != "" {
+			writeErr(w, http.StatusBadRequest, "validation_error", msg)
+			return
+		}
+	}
+
+	resp, err := a.svc.UpdateModel(r.Context(), modelID, req)
+	if err != nil {
+		writeModelErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) setModelActive(w http.ResponseWriter, r *http.Request, modelID int64, active bool) {
+	resp, err := a.svc.SetModelActive(r.Context(), modelID, active)
+	if err != nil {
+		writeModelErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeModelErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrModelNotFound:
+		writeErr(w, http.StatusNotFound, "model_not_found", "model not found")
+	case ErrModelExists:
+		writeErr(w, http.StatusConflict, "model_exists", "model with this name and version already exists")
+	default:
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+	}
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	models "MicroserviceWebsocket/internal/domain"
+)
+
+const (
+	maxTemplateNameLen    = 100
+	maxTemplateContentLen = 20000
+)
+
+// /templates (GET, POST), /templates/{id} (GET, PATCH, DELETE)
+func (a *API) Templates(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/templates"), "/")
+
+	if idStr == "" {
+		switch r.Method {
+		case http.MethodGet:
+			a.listTemplates(w, r)
+		case http.MethodPost:
+			a.createTemplate(w, r)
+		default:
+			writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeErr(w, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.getTemplate(w, r, id)
+	case http.MethodPatch:
+		a.updateTemplate(w, r, id)
+	case http.MethodDelete:
+		a.deleteTemplate(w, r, id)
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	}
+}
+
+func writeTemplateErr(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrTemplateNotFound:
+		writeErr(w, http.StatusNotFound, "template_not_found", "template not found")
+	case ErrNameTaken:
+		writeErr(w, http.StatusConflict, "name_taken", "template with this name already exists")
+	default:
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+	}
+}
+
+func (a *API) listTemplates(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := a.svc.ListPromptTemplates(r.Context(), userID)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "internal", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) getTemplate(w http.ResponseWriter, r *http.Request, id int64) {
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	resp, err := a.svc.GetPromptTemplate(r.Context(), userID, id)
+	if err != nil {
+		writeTemplateErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decodeTemplateReq читает тело и нормализует name/content; create — оба обязательны
+func decodeTemplateReq(w http.ResponseWriter, r *http.Request, create bool) (models.PromptTemplateReq, bool) {
+	var req models.PromptTemplateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, "bad_json", "invalid json body")
+		return req, false
+	}
+	if req.UserID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "user_id is required")
+		return req, false
+	}
+	if create && (req.Name == nil || req.Content == nil) {
+		writeErr(w, http.StatusBadRequest, "validation_error", "name and content are required")
+		return req, false
+	}
+	if !create && req.Name == nil && req.Content == nil && req.IsShared == nil {
+		writeErr(w, http.StatusBadRequest, "validation_error", "nothing to update")
+		return req, false
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxTemplateNameLen {
+			writeErr(w, http.StatusBadRequest, "validation_error", "name must be 1..100 characters")
+			return req, false
+		}
+		req.Name = &name
+	}
+	if req.Content != nil {
+		if strings.TrimSpace(*req.Content) == "" || utf8.RuneCountInString(*req.Content) > maxTemplateContentLen {
+			writeErr(w, http.StatusBadRequest, "validation_error", "content must be 1..20000 characters")
+			return req, false
+		}
+	}
+	return req, true
+}
+
+func (a *API) createTemplate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTemplateReq(w, r, true)
+	if !ok {
+		return
+	}
+
+	resp, err := a.svc.CreatePromptTemplate(r.Context(), req)
+	if err != nil {
+		writeTemplateErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (a *API) updateTemplate(w http.ResponseWriter, r *http.Request, id int64) {
+	req, ok := decodeTemplateReq(w, r, false)
+	if !ok {
+		return
+	}
+
+	resp, err := a.svc.UpdatePromptTemplate(r.Context(), id, req)
+	if err != nil {
+		writeTemplateErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *API) deleteTemplate(w http.ResponseWriter, r *http.Request, id int64) {
+	userIDStr := r.URL.Query().Get("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		writeErr(w, http.StatusBadRequest, "validation_error", "query user_id is required and must be int64")
+		return
+	}
+
+	if err := a.svc.DeletePromptTemplate(r.Context(), userID, id); err != nil {
+		writeTemplateErr(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package dataset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/redact"
+)
+
+// Source — потоковое чтение оценённых ответов (postgresql.Storage)
+type Source interface {
+	EachDatasetRecord(ctx context.Context, f models.DatasetFilter, fn func(models.DatasetRecord) error) error
+}
+
+// FileName — имя файла для Content-Disposition и CLI по умолчанию
+func FileName(now time.Time) string {
+	return fmt.Sprintf("dataset-%s.jsonl", now.UTC().Format("20060102-150405"))
+}
+
+// WriteJSONL пишет по записи на строку, не собирая выгрузку в память.
+// Возвращает число записанных строк.
+func WriteJSONL(ctx context.Context, w io.Writer, src Source, f models.DatasetFilter) (int, error) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	n := 0
+	err := src.EachDatasetRecord(ctx, f, func(rec models.DatasetRecord) error {
+		if f.Redact {
+			redactRecord(&rec)
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+func redactRecord(rec *models.DatasetRecord) {
+	rec.Prompt = redact.String(rec.Prompt)
+	rec.Response = redact.String(rec.Response)
+	for i := range rec.Context {
+		rec.Context[i].Content = redact.String(rec.Context[i].Content)
+	}
+}
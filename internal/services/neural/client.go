@@ -42,6 +42,12 @@ type Client struct {
 
 	// чтобы не запускать параллельно несколько reconnect
 	reconnectMu sync.Mutex
+
+	// ready закрывается при первом подключении, closed — в Close (останавливает redial)
+	ready     chan struct{}
+	readyOnce sync.Once
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 type result struct {
@@ -59,6 +65,8 @@ func NewClient(neuralURL string, timeout time.Duration) *Client {
 		timeout: timeout,
 		writeCh: make(chan any, 256),
 		pending: make(map[string]chan result),
+		ready:   make(chan struct{}),
+		closed:  make(chan struct{}),
 	}
 	go c.connectLoop()
 	return c
@@ -66,6 +74,8 @@ func NewClient(neuralURL string, timeout time.Duration) *Client {
 
 // Close — корректно останавливает клиент и завершает pending
 func (c *Client) Close() {
+	c.closeOnce.Do(func() { close(c.closed) })
+
 	c.reconnectMu.Lock()
 	defer c.reconnectMu.Unlock()
 
@@ -93,13 +103,25 @@ func (c *Client) connectLoop() {
 	for {
 		if err := c.connectOnce(); err != nil {
 			log.Printf("Failed to connect to neural service: %v. Retrying in 5s...", err)
-			time.Sleep(5 * time.Second)
+			if !c.sleepOrClosed(5 * time.Second) {
+				return
+			}
 			continue
 		}
 		return
 	}
 }
 
+// sleepOrClosed ждёт d; false — клиент закрыт, повторять не нужно
+func (c *Client) sleepOrClosed(d time.Duration) bool {
+	select {
+	case <-c.closed:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
 // connect
 func (c *Client) connectOnce() error {
 	conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
@@ -110,8 +132,8 @@ func (c *Client) connectOnce() error {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	c.mu.Lock()
-	// если кто-то уже подключил — закрываем новое
-	if c.conn != nil {
+	// клиент закрыт, пока шёл dial, или кто-то уже подключил — закрываем новое
+	if c.isClosed() || c.conn != nil {
 		c.mu.Unlock()
 		cancel()
 		_ = conn.Close()
@@ -121,6 +143,7 @@ func (c *Client) connectOnce() error {
 	c.isReady = true
 	c.stopConn = cancel
 	c.mu.Unlock()
+	c.readyOnce.Do(func() { close(c.ready) })
 
 	log.Printf("Connected to neural service: %s", c.url)
 
@@ -131,6 +154,10 @@ func (c *Client) connectOnce() error {
 }
 
 func (c *Client) reconnect(reason error) {
+	if c.isClosed() {
+		return
+	}
+
 	c.reconnectMu.Lock()
 	defer c.reconnectMu.Unlock()
 
@@ -163,7 +190,9 @@ func (c *Client) reconnect(reason error) {
 	for {
 		if err := c.connectOnce(); err != nil {
 			log.Printf("Reconnect failed: %v. Retrying in 5s...", err)
-			time.Sleep(5 * time.Second)
+			if !c.sleepOrClosed(5 * time.Second) {
+				return
+			}
 			continue
 		}
 		return
@@ -252,6 +281,27 @@ func (c *Client) readLoop(ctx context.Context, conn *websocket.Conn) {
 	}
 }
 
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitReady ждёт первого подключения, но не дольше timeout
+func (c *Client) waitReady(timeout time.Duration) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case <-c.ready:
+	case <-c.closed:
+	case <-t.C:
+	}
+}
+
 // ===== public API =====
 
 // ProcessSingle отправляет один запрос и ждёт ответ по uuid
@@ -287,6 +337,7 @@ func (c *Client) ProcessSingle(request models.Request) (models.Response, error)
 		ModelName: request.ModelName,
 		Message:   request.Message,
 		ChatUUID:  request.ChatUUID,
+		Settings:  request.Settings,
 	}
 
 	// отправляем через writer-очередь
@@ -308,9 +359,11 @@ func (c *Client) ProcessSingle(request models.Request) (models.Response, error)
 		}
 		// адаптируем обратно в ваш models.Response
 		return models.Response{
-			UUID:      r.resp.UUID,
-			Response:  r.resp.Response,
-			CreatedAt: r.resp.CreatedAt,
+			UUID:           r.resp.UUID,
+			Response:       r.resp.Response,
+			CreatedAt:      r.resp.CreatedAt,
+			PromptTokens:   r.resp.PromptTokens,
+			ResponseTokens: r.resp.ResponseTokens,
 		}, nil
 
 	case <-time.After(c.timeout):
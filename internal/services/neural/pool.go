@@ -0,0 +1,132 @@
+package neural
+
+import (
+	"sync"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+)
+
+// клиент отдельного gateway, к которому давно не обращались, закрывается:
+// endpoint модели могли сменить или модель выключить, а redial шёл бы вечно
+const (
+	idleTimeout   = 10 * time.Minute
+	evictInterval = time.Minute
+)
+
+type poolEntry struct {
+	client   *Client
+	inflight int
+	lastUsed time.Time
+}
+
+// Pool — по соединению на gateway: модели с bot_models.endpoint идут в свой
+// адрес, остальные — в общий URLNeural из конфига
+type Pool struct {
+	defaultURL string
+	timeout    time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*poolEntry
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewPool(defaultURL string, timeout time.Duration) *Pool {
+	p := &Pool{
+		defaultURL: defaultURL,
+		timeout:    timeout,
+		clients:    make(map[string]*poolEntry),
+		stop:       make(chan struct{}),
+	}
+	// общий gateway подключаем сразу и не выселяем, остальные — при первом запросе
+	p.clients[defaultURL] = &poolEntry{client: NewClient(defaultURL, timeout), lastUsed: time.Now()}
+	go p.evictLoop()
+	return p
+}
+
+// acquire возвращает соединение для endpoint ("" — общий gateway) и помечает
+// его занятым до release
+func (p *Pool) acquire(endpoint string) *Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.clients[endpoint]
+	if !ok {
+		e = &poolEntry{client: NewClient(endpoint, p.timeout)}
+		p.clients[endpoint] = e
+	}
+	e.inflight++
+	e.lastUsed = time.Now()
+	return e.client
+}
+
+func (p *Pool) release(endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.clients[endpoint]; ok {
+		e.inflight--
+		e.lastUsed = time.Now()
+	}
+}
+
+// ProcessSingle отправляет запрос в gateway модели и ждёт ответ.
+// Новое соединение ждут все первые запросы, а не только открывший его.
+func (p *Pool) ProcessSingle(endpoint string, request models.Request) (models.Response, error) {
+	if endpoint == "" {
+		endpoint = p.defaultURL
+	}
+
+	c := p.acquire(endpoint)
+	defer p.release(endpoint)
+
+	c.waitReady(p.timeout)
+	return c.ProcessSingle(request)
+}
+
+func (p *Pool) evictLoop() {
+	ticker := time.NewTicker(evictInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.evictIdle(time.Now())
+		}
+	}
+}
+
+// evictIdle закрывает простаивающие соединения с отдельными gateway
+func (p *Pool) evictIdle(now time.Time) {
+	p.mu.Lock()
+	idle := make([]*Client, 0)
+	for url, e := range p.clients {
+		if url == p.defaultURL || e.inflight > 0 || now.Sub(e.lastUsed) < idleTimeout {
+			continue
+		}
+		idle = append(idle, e.client)
+		delete(p.clients, url)
+	}
+	p.mu.Unlock()
+
+	for _, c := range idle {
+		c.Close()
+	}
+}
+
+// Close закрывает все соединения
+func (p *Pool) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for url, e := range p.clients {
+		e.client.Close()
+		delete(p.clients, url)
+	}
+}
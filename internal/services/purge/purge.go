@@ -0,0 +1,75 @@
+package purge
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"MicroserviceWebsocket/internal/config"
+	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/logger/sl"
+)
+
+type Storage interface {
+	PurgeDeleted(ctx context.Context, cutoff time.Time, batchSize int, dryRun bool) (models.PurgeReport, error)
+}
+
+// Purger периодически удаляет soft-deleted строки старше retention
+type Purger struct {
+	log       *slog.Logger
+	storage   Storage
+	interval  time.Duration
+	retention time.Duration
+	batchSize int
+	dryRun    bool
+}
+
+func New(log *slog.Logger, storage Storage, cfg config.Purge) *Purger {
+	return &Purger{
+		log:       log,
+		storage:   storage,
+		interval:  cfg.Interval,
+		retention: cfg.Retention,
+		batchSize: cfg.BatchSize,
+		dryRun:    cfg.DryRun,
+	}
+}
+
+// RunOnce выполняет один проход очистки
+func (p *Purger) RunOnce(ctx context.Context) (models.PurgeReport, error) {
+	const op = "purge.RunOnce"
+
+	cutoff := time.Now().Add(-p.retention)
+	report, err := p.storage.PurgeDeleted(ctx, cutoff, p.batchSize, p.dryRun)
+	if err != nil {
+		p.log.Error("purge failed", slog.String("op", op), sl.Err(err))
+		return report, err
+	}
+
+	p.log.Info("purge finished",
+		slog.String("op", op),
+		slog.String("cutoff", report.Cutoff),
+		slog.Bool("dry_run", report.DryRun),
+		slog.Int64("chats", report.Chats),
+		slog.Int64("messages", report.Messages),
+		slog.Int64("feedbacks", report.Feedbacks),
+	)
+	return report, nil
+}
+
+// Run крутит RunOnce по таймеру до отмены ctx
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		_, _ = p.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
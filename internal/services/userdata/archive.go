@@ -0,0 +1,80 @@
+package userdata
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+)
+
+type manifest struct {
+	UserID      int64  `json:"user_id"`
+	ExportedAt  string `json:"exported_at"`
+	Chats       int    `json:"chats"`
+	Messages    int    `json:"messages"`
+	Memberships int    `json:"memberships"`
+	Feedbacks   int    `json:"feedbacks"`
+	Usage       int    `json:"usage"`
+	Folders     int    `json:"folders"`
+	Tags        int    `json:"tags"`
+	Shares      int    `json:"shares"`
+	Templates   int    `json:"templates"`
+	Arena       int    `json:"arena_preferences"`
+}
+
+// FileName — имя архива для Content-Disposition и CLI по умолчанию
+func FileName(userID int64) string {
+	return fmt.Sprintf("user-%d-data.zip", userID)
+}
+
+// WriteArchive пишет zip: manifest.json + по json-файлу на таблицу
+func WriteArchive(w io.Writer, data models.UserData) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"manifest.json", manifest{
+			UserID:      data.UserID,
+			ExportedAt:  time.Now().UTC().Format(time.RFC3339),
+			Chats:       len(data.Chats),
+			Messages:    len(data.Messages),
+			Memberships: len(data.Memberships),
+			Feedbacks:   len(data.Feedbacks),
+			Usage:       len(data.Usage),
+			Folders:     len(data.Folders),
+			Tags:        len(data.Tags),
+			Shares:      len(data.Shares),
+			Templates:   len(data.Templates),
+			Arena:       len(data.Arena),
+		}},
+		{"chats.json", data.Chats},
+		{"messages.json", data.Messages},
+		{"memberships.json", data.Memberships},
+		{"feedbacks.json", data.Feedbacks},
+		{"usage.json", data.Usage},
+		{"folders.json", data.Folders},
+		{"tags.json", data.Tags},
+		{"shares.json", data.Shares},
+		{"templates.json", data.Templates},
+		{"arena_preferences.json", data.Arena},
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("userdata.WriteArchive: %s: %w", f.name, err)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return fmt.Errorf("userdata.WriteArchive: %s: %w", f.name, err)
+		}
+	}
+
+	return zw.Close()
+}
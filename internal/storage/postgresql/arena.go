@@ -0,0 +1,145 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
+)
+
+// ArenaSettings — параметры генерации чата поверх default_params каждой из моделей
+// arena (в порядке modelIDs). Неактивная или неизвестная модель — ErrModelNotFound.
+func (s *Storage) ArenaSettings(ctx context.Context, chatUUID string, modelIDs []int64) ([]models.ChatSettingsResp, error) {
+	const op = "storage.postgres.ArenaSettings"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.id, m.name, COALESCE(m.context_size, 0), COALESCE(m.endpoint, ''),
+		       c.settings, m.default_params, m.default_params || c.settings
+		FROM chats c
+		JOIN bot_models m ON m.id = ANY($2::bigint[]) AND m.is_active = TRUE
+		WHERE c.chat_uuid = $1::uuid
+	`, chatUUID, modelIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]models.ChatSettingsResp, len(modelIDs))
+	for rows.Next() {
+		resp := models.ChatSettingsResp{ChatUUID: chatUUID}
+		var settings, defaults, effective []byte
+		if err := rows.Scan(&resp.ModelID, &resp.ModelName, &resp.ContextSize, &resp.Endpoint, &settings, &defaults, &effective); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal(settings, &resp.Settings); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal(defaults, &resp.Defaults); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal(effective, &resp.Effective); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		// max_tokens чата мог быть подобран под другую модель
+		if p := resp.Effective.MaxTokens; p != nil && resp.ContextSize > 0 && *p > resp.ContextSize {
+			resp.Effective.MaxTokens = resp.Defaults.MaxTokens
+		}
+		byID[resp.ModelID] = resp
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items := make([]models.ChatSettingsResp, 0, len(modelIDs))
+	for _, id := range modelIDs {
+		resp, ok := byID[id]
+		if !ok {
+			return nil, httpAPI.ErrModelNotFound
+		}
+		items = append(items, resp)
+	}
+	return items, nil
+}
+
+// SetArenaPreference запоминает выбор лучшего ответа: upsert по (промпт, пользователь).
+// Ответ должен быть одним из ответов разных моделей на один промпт.
+func (s *Storage) SetArenaPreference(ctx context.Context, userID int64, messageUUID string) (models.ArenaPreferenceResp, error) {
+	const op = "storage.postgres.SetArenaPreference"
+
+	resp := models.ArenaPreferenceResp{MessageID: messageUUID, ComparedWith: make([]int64, 0, 4)}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return resp, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// 1) message exists, role=bot, not deleted; выбирать может любой участник чата.
+	// model_id — модель, написавшая ответ, как и у оценок
+	modelID, err := feedbackTarget(ctx, tx, messageUUID, userID)
+	if err != nil {
+		return resp, err
+	}
+
+	var prompt sql.NullString
+	if err := tx.QueryRowContext(ctx, `
+		SELECT reply_to_message_id FROM messages WHERE message_uuid = $1::uuid
+	`, messageUUID).Scan(&prompt); err != nil {
+		return resp, fmt.Errorf("%s: message: %w", op, err)
+	}
+	if !prompt.Valid {
+		return resp, httpAPI.ErrNotArenaMessage
+	}
+	resp.PromptMessageID, resp.ModelID = prompt.String, modelID
+
+	// 2) остальные модели, ответившие на тот же промпт
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT model_id
+		FROM messages
+		WHERE reply_to_message_id = $1::uuid AND role = 'bot' AND is_deleted = FALSE
+		  AND model_id IS NOT NULL AND model_id <> $2
+		ORDER BY model_id
+	`, prompt.String, modelID)
+	if err != nil {
+		return resp, fmt.Errorf("%s: siblings: %w", op, err)
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return resp, fmt.Errorf("%s: siblings: %w", op, err)
+		}
+		resp.ComparedWith = append(resp.ComparedWith, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return resp, fmt.Errorf("%s: siblings: %w", op, err)
+	}
+	if len(resp.ComparedWith) == 0 {
+		return resp, httpAPI.ErrNotArenaMessage
+	}
+
+	// 3) upsert
+	var updated time.Time
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO arena_preferences (prompt_message_uuid, chosen_message_uuid, chosen_model_id, user_id)
+		VALUES ($1::uuid, $2::uuid, $3, $4)
+		ON CONFLICT (prompt_message_uuid, user_id) WHERE user_id <> 0
+		DO UPDATE SET chosen_message_uuid = EXCLUDED.chosen_message_uuid,
+		              chosen_model_id     = EXCLUDED.chosen_model_id
+		RETURNING updated_at
+	`, prompt.String, messageUUID, modelID, userID).Scan(&updated)
+	if err != nil {
+		return resp, fmt.Errorf("%s: upsert: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return resp, fmt.Errorf("%s: commit: %w", op, err)
+	}
+	resp.UpdatedAt = updated.UTC().Format(time.RFC3339)
+	return resp, nil
+}
@@ -0,0 +1,156 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
+)
+
+const botModelSelect = `
+	SELECT id, name, version, is_active, COALESCE(endpoint, ''), COALESCE(context_size, 0),
+	       default_params, daily_token_quota, monthly_token_quota, created_at, updated_at
+	FROM bot_models
+`
+
+func scanBotModel(row interface{ Scan(...any) error }) (models.BotModel, error) {
+	var m models.BotModel
+	var params []byte
+	var daily, monthly sql.NullInt64
+	var created, updated time.Time
+	if err := row.Scan(&m.ID, &m.Name, &m.Version, &m.IsActive, &m.Endpoint, &m.ContextSize,
+		&params, &daily, &monthly, &created, &updated); err != nil {
+		return models.BotModel{}, err
+	}
+	if err := json.Unmarshal(params, &m.DefaultParams); err != nil {
+		return models.BotModel{}, err
+	}
+	if daily.Valid {
+		m.DailyTokenQuota = &daily.Int64
+	}
+	if monthly.Valid {
+		m.MonthlyTokenQuota = &monthly.Int64
+	}
+	m.CreatedAt = created.UTC().Format(time.RFC3339)
+	m.UpdatedAt = updated.UTC().Format(time.RFC3339)
+	return m, nil
+}
+
+// quotaArg: nil и 0 — без ограничений (NULL)
+func quotaArg(q *int64) sql.NullInt64 {
+	if q == nil || *q == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *q, Valid: true}
+}
+
+// ListModels — все модели (admin) или только активные (выбор модели на фронте)
+func (s *Storage) ListModels(ctx context.Context, activeOnly bool) ([]models.BotModel, error) {
+	rows, err := s.db.QueryContext(ctx, botModelSelect+`
+		WHERE is_active = TRUE OR NOT $1
+		ORDER BY name, version
+	`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.BotModel, 0, 8)
+	for rows.Next() {
+		m, err := scanBotModel(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	return items, rows.Err()
+}
+
+func (s *Storage) GetModel(ctx context.Context, modelID int64) (models.BotModel, error) {
+	m, err := scanBotModel(s.db.QueryRowContext(ctx, botModelSelect+`
+		WHERE id = $1
+	`, modelID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.BotModel{}, httpAPI.ErrModelNotFound
+	}
+	return m, err
+}
+
+func (s *Storage) CreateModel(ctx context.Context, req models.CreateModelReq) (models.BotModel, error) {
+	params, err := json.Marshal(req.DefaultParams)
+	if err != nil {
+		return models.BotModel{}, err
+	}
+	active := true
+	if req.IsActive != nil {
+		active = *req.IsActive
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO bot_models (name, version, is_active, endpoint, context_size, default_params,
+		                        daily_token_quota, monthly_token_quota)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, 0), $6, $7, $8)
+		ON CONFLICT (name, version) DO NOTHING
+		RETURNING id
+	`, req.Name, req.Version, active, req.Endpoint, req.ContextSize, string(params),
+		quotaArg(req.DailyTokenQuota), quotaArg(req.MonthlyTokenQuota)).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.BotModel{}, httpAPI.ErrModelExists
+		}
+		return models.BotModel{}, err
+	}
+
+	return s.GetModel(ctx, id)
+}
+
+// UpdateModel меняет только переданные поля
+func (s *Storage) UpdateModel(ctx context.Context, modelID int64, req models.UpdateModelReq) (models.BotModel, error) {
+	var params sql.NullString
+	if req.DefaultParams != nil {
+		b, err := json.Marshal(*req.DefaultParams)
+		if err != nil {
+			return models.BotModel{}, err
+		}
+		params = sql.NullString{String: string(b), Valid: true}
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE bot_models
+		SET endpoint            = CASE WHEN $2::text IS NULL THEN endpoint ELSE NULLIF($2, '') END,
+		    context_size        = CASE WHEN $3::int IS NULL THEN context_size ELSE NULLIF($3, 0) END,
+		    default_params      = COALESCE($4::jsonb, default_params),
+		    daily_token_quota   = CASE WHEN $5::bigint IS NULL THEN daily_token_quota ELSE NULLIF($5, 0) END,
+		    monthly_token_quota = CASE WHEN $6::bigint IS NULL THEN monthly_token_quota ELSE NULLIF($6, 0) END
+		WHERE id = $1
+	`, modelID, req.Endpoint, req.ContextSize, params, req.DailyTokenQuota, req.MonthlyTokenQuota)
+	if err != nil {
+		return models.BotModel{}, err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return models.BotModel{}, httpAPI.ErrModelNotFound
+	}
+
+	return s.GetModel(ctx, modelID)
+}
+
+// SetModelActive включает/выключает модель. Выключенную нельзя выбрать
+// для нового чата, существующие чаты продолжают работать.
+func (s *Storage) SetModelActive(ctx context.Context, modelID int64, active bool) (models.BotModel, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE bot_models SET is_active = $2 WHERE id = $1
+	`, modelID, active)
+	if err != nil {
+		return models.BotModel{}, err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return models.BotModel{}, httpAPI.ErrModelNotFound
+	}
+
+	return s.GetModel(ctx, modelID)
+}
@@ -0,0 +1,148 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	models "MicroserviceWebsocket/internal/domain"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
+)
+
+// effective = default_params модели, поверх — ключи чата (jsonb ||)
+func chatSettings(ctx context.Context, q queryRower, chatUUID string) (models.ChatSettingsResp, error) {
+	resp := models.ChatSettingsResp{ChatUUID: chatUUID}
+	var settings, defaults, effective []byte
+	err := q.QueryRowContext(ctx, `
+		SELECT m.id, m.name, COALESCE(m.context_size, 0), COALESCE(m.endpoint, ''),
+		       c.settings, m.default_params, m.default_params || c.settings
+		FROM chats c
+		JOIN bot_models m ON m.id = c.model_id
+		WHERE c.chat_uuid = $1::uuid
+	`, chatUUID).Scan(&resp.ModelID, &resp.ModelName, &resp.ContextSize, &resp.Endpoint, &settings, &defaults, &effective)
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(settings, &resp.Settings); err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(defaults, &resp.Defaults); err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(effective, &resp.Effective); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// ChatSettings — настройки генерации чата (viewer+)
+func (s *Storage) ChatSettings(ctx context.Context, userID int64, chatUUID string) (models.ChatSettingsResp, error) {
+	const op = "storage.postgres.ChatSettings"
+
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return models.ChatSettingsResp{}, err
+	}
+
+	resp, err := chatSettings(ctx, s.db, chatUUID)
+	if err != nil {
+		return resp, fmt.Errorf("%s: %w", op, err)
+	}
+	return resp, nil
+}
+
+// SetChatSettings заменяет переопределения чата (editor+)
+func (s *Storage) SetChatSettings(ctx context.Context, userID int64, chatUUID string, p models.GenerationParams) (models.ChatSettingsResp, error) {
+	const op = "storage.postgres.SetChatSettings"
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return models.ChatSettingsResp{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return models.ChatSettingsResp{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := chatAccess(ctx, tx, userID, chatUUID, models.RoleEditor); err != nil {
+		return models.ChatSettingsResp{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE chats SET settings = $2::jsonb WHERE chat_uuid = $1::uuid
+	`, chatUUID, string(b)); err != nil {
+		return models.ChatSettingsResp{}, fmt.Errorf("%s: update: %w", op, err)
+	}
+
+	resp, err := chatSettings(ctx, tx, chatUUID)
+	if err != nil {
+		return resp, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ChatSettingsResp{}, fmt.Errorf("%s: commit: %w", op, err)
+	}
+	return resp, nil
+}
+
+// GenerationSettings — текущая модель чата и итоговые параметры для запроса
+// в gateway (доступ уже проверен)
+func (s *Storage) GenerationSettings(ctx context.Context, chatUUID string) (models.ChatSettingsResp, error) {
+	resp, err := chatSettings(ctx, s.db, chatUUID)
+	if err != nil {
+		return resp, fmt.Errorf("storage.postgres.GenerationSettings: %w", err)
+	}
+	return resp, nil
+}
+
+// SwitchChatModel меняет модель чата (editor+); только на активную модель.
+// Старые ответы сохраняют свой messages.model_id. max_tokens чата, не влезающий
+// в context_size новой модели, сбрасывается.
+func (s *Storage) SwitchChatModel(ctx context.Context, userID int64, chatUUID string, modelID int64) (models.ChatModelResp, error) {
+	const op = "storage.postgres.SwitchChatModel"
+
+	resp := models.ChatModelResp{ChatUUID: chatUUID, ModelID: modelID}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return resp, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := chatAccess(ctx, tx, userID, chatUUID, models.RoleEditor); err != nil {
+		return resp, err
+	}
+
+	var contextSize int
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, version, COALESCE(context_size, 0)
+		FROM bot_models
+		WHERE id = $1 AND is_active = TRUE
+	`, modelID).Scan(&resp.ModelName, &resp.ModelVersion, &contextSize)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return resp, httpAPI.ErrModelNotFound
+		}
+		return resp, fmt.Errorf("%s: model: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE chats
+		SET model_id = $2,
+		    settings = CASE
+		        WHEN $3::int > 0 AND (settings->>'max_tokens')::int > $3::int THEN settings - 'max_tokens'
+		        ELSE settings
+		    END
+		WHERE chat_uuid = $1::uuid
+	`, chatUUID, modelID, contextSize); err != nil {
+		return resp, fmt.Errorf("%s: update: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return resp, fmt.Errorf("%s: commit: %w", op, err)
+	}
+	return resp, nil
+}
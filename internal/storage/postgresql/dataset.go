@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+)
+
+// EachDatasetRecord построчно отдаёт оценённые ответы бота: запрос пользователя,
+// до ContextTurns предыдущих сообщений чата, ответ и оценку (одна запись на оценку).
+// Удалённые и стёртые (anonymize) сообщения в выгрузку не попадают.
+func (s *Storage) EachDatasetRecord(ctx context.Context, f models.DatasetFilter, fn func(models.DatasetRecord) error) error {
+	const op = "storage.postgres.EachDatasetRecord"
+
+	args := []any{erasedText, f.ContextTurns}
+	where := `b.role = 'bot' AND b.is_deleted = FALSE AND p.is_deleted = FALSE AND c.is_deleted = FALSE
+		  AND b.content <> $1 AND p.content <> $1`
+	if f.ModelID != nil {
+		args = append(args, *f.ModelID)
+		where += fmt.Sprintf(" AND fb.model_id = $%d", len(args))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		where += fmt.Sprintf(" AND fb.created_at >= $%d", len(args))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		where += fmt.Sprintf(" AND fb.created_at < $%d", len(args))
+	}
+	switch f.Rating {
+	case "positive":
+		where += " AND fb.is_positive = TRUE"
+	case "negative":
+		where += " AND fb.is_positive = FALSE"
+	}
+
+	// версия — у самого ответа (model_id сообщения), для старых строк — из оценки
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT b.message_uuid, bm.name, bm.version,
+		       COALESCE((
+		           SELECT json_agg(json_build_object('role', h.role, 'content', h.content) ORDER BY h.created_at)
+		           FROM (
+		               SELECT role, content, created_at
+		               FROM messages
+		               WHERE chat_uuid = p.chat_uuid AND is_deleted = FALSE
+		                 AND created_at < p.created_at
+		               ORDER BY created_at DESC
+		               LIMIT $2
+		           ) h
+		       ), '[]'),
+		       p.content, b.content, fb.is_positive, COALESCE(fb.reason, ''), fb.created_at
+		FROM message_feedbacks fb
+		JOIN messages b ON b.message_uuid = fb.message_uuid
+		JOIN messages p ON p.message_uuid = b.reply_to_message_id
+		JOIN chats c ON c.chat_uuid = b.chat_uuid
+		JOIN bot_models bm ON bm.id = COALESCE(b.model_id, fb.model_id)
+		WHERE %s
+		ORDER BY fb.created_at, fb.id
+	`, where), args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec models.DatasetRecord
+		var history []byte
+		var positive bool
+		var rated time.Time
+		if err := rows.Scan(&rec.MessageUUID, &rec.ModelName, &rec.ModelVersion, &history,
+			&rec.Prompt, &rec.Response, &positive, &rec.Reason, &rated); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal(history, &rec.Context); err != nil {
+			return fmt.Errorf("%s: context: %w", op, err)
+		}
+		rec.Rating = "negative"
+		if positive {
+			rec.Rating = "positive"
+		}
+		rec.RatedAt = rated.UTC().Format(time.RFC3339)
+
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
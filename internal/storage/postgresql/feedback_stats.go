@@ -0,0 +1,155 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
+)
+
+func withRate(pos, neg int64) models.FeedbackCounts {
+	c := models.FeedbackCounts{Positive: pos, Negative: neg}
+	if pos+neg > 0 {
+		c.PositiveRate = float64(pos) / float64(pos+neg)
+	}
+	return c
+}
+
+// FeedbackStats агрегирует оценки модели за [From, To): по бакетам времени,
+// по причинам и последние примеры (отдельно для positive и negative)
+func (s *Storage) FeedbackStats(ctx context.Context, modelID int64, f models.FeedbackStatsFilter) (models.FeedbackStatsResp, error) {
+	const op = "storage.postgres.FeedbackStats"
+
+	resp := models.FeedbackStatsResp{
+		ModelID: modelID,
+		From:    f.From.UTC().Format(time.RFC3339),
+		To:      f.To.UTC().Format(time.RFC3339),
+		Bucket:  f.Bucket,
+		Buckets: make([]models.FeedbackBucket, 0, 32),
+		Reasons: make([]models.FeedbackReason, 0, 8),
+		Samples: make([]models.FeedbackSample, 0, 2*f.Samples),
+	}
+
+	// все запросы из одного снимка
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return resp, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// 1) model
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, version FROM bot_models WHERE id = $1
+	`, modelID).Scan(&resp.ModelName, &resp.ModelVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return resp, httpAPI.ErrModelNotFound
+		}
+		return resp, fmt.Errorf("%s: model: %w", op, err)
+	}
+
+	// 2) buckets (границы в UTC); total = сумма бакетов
+	rows, err := tx.QueryContext(ctx, `
+		SELECT date_trunc($4, created_at, 'UTC') AS bucket,
+		       COUNT(*) FILTER (WHERE is_positive),
+		       COUNT(*) FILTER (WHERE NOT is_positive)
+		FROM message_feedbacks
+		WHERE model_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY bucket
+		ORDER BY bucket
+	`, modelID, f.From, f.To, f.Bucket)
+	if err != nil {
+		return resp, fmt.Errorf("%s: buckets: %w", op, err)
+	}
+	var totalPos, totalNeg int64
+	for rows.Next() {
+		var start time.Time
+		var pos, neg int64
+		if err := rows.Scan(&start, &pos, &neg); err != nil {
+			rows.Close()
+			return resp, fmt.Errorf("%s: buckets: %w", op, err)
+		}
+		resp.Buckets = append(resp.Buckets, models.FeedbackBucket{
+			Start:          start.UTC().Format(time.RFC3339),
+			FeedbackCounts: withRate(pos, neg),
+		})
+		totalPos += pos
+		totalNeg += neg
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return resp, fmt.Errorf("%s: buckets: %w", op, err)
+	}
+	resp.Total = withRate(totalPos, totalNeg)
+
+	// 3) reasons (без причины не считаем)
+	rows, err = tx.QueryContext(ctx, `
+		SELECT reason,
+		       COUNT(*) FILTER (WHERE is_positive),
+		       COUNT(*) FILTER (WHERE NOT is_positive)
+		FROM message_feedbacks
+		WHERE model_id = $1 AND created_at >= $2 AND created_at < $3
+		  AND reason IS NOT NULL
+		GROUP BY reason
+		ORDER BY COUNT(*) DESC, reason
+	`, modelID, f.From, f.To)
+	if err != nil {
+		return resp, fmt.Errorf("%s: reasons: %w", op, err)
+	}
+	for rows.Next() {
+		var r models.FeedbackReason
+		var pos, neg int64
+		if err := rows.Scan(&r.Reason, &pos, &neg); err != nil {
+			rows.Close()
+			return resp, fmt.Errorf("%s: reasons: %w", op, err)
+		}
+		r.FeedbackCounts = withRate(pos, neg)
+		resp.Reasons = append(resp.Reasons, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return resp, fmt.Errorf("%s: reasons: %w", op, err)
+	}
+
+	// 4) samples: последние N по каждой оценке, скрытые сообщения не показываем
+	if f.Samples > 0 {
+		rows, err = tx.QueryContext(ctx, `
+			SELECT message_uuid, chat_uuid, is_positive, reason, comment, content, created_at
+			FROM (
+				SELECT f.message_uuid, m.chat_uuid, f.is_positive,
+				       COALESCE(f.reason, '') AS reason, COALESCE(f.comment, '') AS comment,
+				       m.content, f.created_at,
+				       ROW_NUMBER() OVER (PARTITION BY f.is_positive ORDER BY f.created_at DESC) AS rn
+				FROM message_feedbacks f
+				JOIN messages m ON m.message_uuid = f.message_uuid
+				WHERE f.model_id = $1 AND f.created_at >= $2 AND f.created_at < $3
+				  AND m.is_deleted = FALSE
+			) s
+			WHERE rn <= $4
+			ORDER BY is_positive, created_at DESC
+		`, modelID, f.From, f.To, f.Samples)
+		if err != nil {
+			return resp, fmt.Errorf("%s: samples: %w", op, err)
+		}
+		for rows.Next() {
+			var sm models.FeedbackSample
+			var created time.Time
+			if err := rows.Scan(&sm.MessageUUID, &sm.ChatUUID, &sm.IsPositive, &sm.Reason, &sm.Comment, &sm.Content, &created); err != nil {
+				rows.Close()
+				return resp, fmt.Errorf("%s: samples: %w", op, err)
+			}
+			sm.CreatedAt = created.UTC().Format(time.RFC3339)
+			resp.Samples = append(resp.Samples, sm)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return resp, fmt.Errorf("%s: samples: %w", op, err)
+		}
+	}
+
+	return resp, nil
+}
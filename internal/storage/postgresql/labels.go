@@ -0,0 +1,221 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// labelTable — folders и tags устроены одинаково: личные, имя уникально у пользователя
+type labelTable struct {
+	table    string // folders|tags
+	link     string // folder_chats|chat_tags
+	linkCol  string // folder_id|tag_id
+	notFound error
+}
+
+var (
+	folderTable = labelTable{table: "folders", link: "folder_chats", linkCol: "folder_id", notFound: httpAPI.ErrFolderNotFound}
+	tagTable    = labelTable{table: "tags", link: "chat_tags", linkCol: "tag_id", notFound: httpAPI.ErrTagNotFound}
+)
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (t labelTable) list(ctx context.Context, db *sql.DB, userID int64) (models.ListLabelsResp, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT l.id, l.name, l.created_at,
+		       (SELECT COUNT(*) FROM %s x WHERE x.%s = l.id)
+		FROM %s l
+		WHERE l.user_id = $1
+		ORDER BY l.name, l.id
+	`, t.link, t.linkCol, t.table), userID)
+	if err != nil {
+		return models.ListLabelsResp{}, err
+	}
+	defer rows.Close()
+
+	resp := models.ListLabelsResp{Items: make([]models.Label, 0, 16)}
+	for rows.Next() {
+		var l models.Label
+		var created time.Time
+		if err := rows.Scan(&l.ID, &l.Name, &created, &l.Chats); err != nil {
+			return models.ListLabelsResp{}, err
+		}
+		l.CreatedAt = created.UTC().Format(time.RFC3339)
+		resp.Items = append(resp.Items, l)
+	}
+	if err := rows.Err(); err != nil {
+		return models.ListLabelsResp{}, err
+	}
+	return resp, nil
+}
+
+func (t labelTable) create(ctx context.Context, db *sql.DB, userID int64, name string) (models.Label, error) {
+	l := models.Label{Name: name}
+	var created time.Time
+	err := db.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING id, created_at
+	`, t.table), userID, name).Scan(&l.ID, &created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Label{}, httpAPI.ErrNameTaken
+		}
+		return models.Label{}, err
+	}
+	l.CreatedAt = created.UTC().Format(time.RFC3339)
+	return l, nil
+}
+
+func (t labelTable) rename(ctx context.Context, db *sql.DB, userID, id int64, name string) (models.Label, error) {
+	l := models.Label{ID: id, Name: name}
+	var created time.Time
+	err := db.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %s l
+		SET name = $3
+		WHERE l.id = $1 AND l.user_id = $2
+		RETURNING l.created_at, (SELECT COUNT(*) FROM %s x WHERE x.%s = l.id)
+	`, t.table, t.link, t.linkCol), id, userID, name).Scan(&created, &l.Chats)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Label{}, t.notFound
+		}
+		if isUniqueViolation(err) {
+			return models.Label{}, httpAPI.ErrNameTaken
+		}
+		return models.Label{}, err
+	}
+	l.CreatedAt = created.UTC().Format(time.RFC3339)
+	return l, nil
+}
+
+// delete удаляет папку/тег; сами чаты остаются (связи уходят каскадом)
+func (t labelTable) delete(ctx context.Context, db *sql.DB, userID, id int64) error {
+	res, err := db.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s WHERE id = $1 AND user_id = $2
+	`, t.table), id, userID)
+	if err != nil {
+		return err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return t.notFound
+	}
+	return nil
+}
+
+// owned проверяет, что папка/тег принадлежит пользователю
+func (t labelTable) owned(ctx context.Context, q queryRower, userID, id int64) error {
+	var one int
+	err := q.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT 1 FROM %s WHERE id = $1 AND user_id = $2
+	`, t.table), id, userID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t.notFound
+	}
+	return err
+}
+
+// --- folders ---
+
+func (s *Storage) ListFolders(ctx context.Context, userID int64) (models.ListLabelsResp, error) {
+	return folderTable.list(ctx, s.db, userID)
+}
+
+func (s *Storage) CreateFolder(ctx context.Context, userID int64, name string) (models.Label, error) {
+	return folderTable.create(ctx, s.db, userID, name)
+}
+
+func (s *Storage) RenameFolder(ctx context.Context, userID, folderID int64, name string) (models.Label, error) {
+	return folderTable.rename(ctx, s.db, userID, folderID, name)
+}
+
+func (s *Storage) DeleteFolder(ctx context.Context, userID, folderID int64) error {
+	return folderTable.delete(ctx, s.db, userID, folderID)
+}
+
+// SetChatFolder кладёт чат в папку (или убирает, если folderID == nil).
+// Достаточно быть участником чата: папки у каждого свои.
+func (s *Storage) SetChatFolder(ctx context.Context, userID int64, chatUUID string, folderID *int64) error {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return err
+	}
+
+	if folderID == nil {
+		_, err := s.db.ExecContext(ctx, `
+			DELETE FROM folder_chats WHERE user_id = $1 AND chat_uuid = $2::uuid
+		`, userID, chatUUID)
+		return err
+	}
+
+	if err := folderTable.owned(ctx, s.db, userID, *folderID); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO folder_chats (user_id, chat_uuid, folder_id)
+		VALUES ($1, $2::uuid, $3)
+		ON CONFLICT (user_id, chat_uuid)
+		DO UPDATE SET folder_id = EXCLUDED.folder_id, created_at = NOW()
+	`, userID, chatUUID, *folderID)
+	return err
+}
+
+// --- tags ---
+
+func (s *Storage) ListTags(ctx context.Context, userID int64) (models.ListLabelsResp, error) {
+	return tagTable.list(ctx, s.db, userID)
+}
+
+func (s *Storage) CreateTag(ctx context.Context, userID int64, name string) (models.Label, error) {
+	return tagTable.create(ctx, s.db, userID, name)
+}
+
+func (s *Storage) RenameTag(ctx context.Context, userID, tagID int64, name string) (models.Label, error) {
+	return tagTable.rename(ctx, s.db, userID, tagID, name)
+}
+
+func (s *Storage) DeleteTag(ctx context.Context, userID, tagID int64) error {
+	return tagTable.delete(ctx, s.db, userID, tagID)
+}
+
+// AddChatTag вешает тег на чат; повторно — без ошибки
+func (s *Storage) AddChatTag(ctx context.Context, userID int64, chatUUID string, tagID int64) error {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return err
+	}
+	if err := tagTable.owned(ctx, s.db, userID, tagID); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO chat_tags (tag_id, chat_uuid)
+		VALUES ($1, $2::uuid)
+		ON CONFLICT DO NOTHING
+	`, tagID, chatUUID)
+	return err
+}
+
+func (s *Storage) RemoveChatTag(ctx context.Context, userID int64, chatUUID string, tagID int64) error {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return err
+	}
+	if err := tagTable.owned(ctx, s.db, userID, tagID); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM chat_tags WHERE tag_id = $1 AND chat_uuid = $2::uuid
+	`, tagID, chatUUID)
+	return err
+}
@@ -0,0 +1,67 @@
+package postgresql
+
+import (
+	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/cursor"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageQuery — направление keyset-выборки.
+// before (и без курсора) идём от новых к старым, after — от старых к новым.
+type pageQuery struct {
+	limit int
+	cur   *cursor.Cursor
+	cmp   string // "<" | ">" для сравнения (ts, uuid) с курсором
+	order string // "DESC" | "ASC"
+	after bool
+}
+
+func newPageQuery(page models.PageReq) (pageQuery, error) {
+	q := pageQuery{limit: page.Limit, cmp: "<", order: "DESC"}
+	if q.limit <= 0 {
+		q.limit = defaultPageLimit
+	}
+	if q.limit > maxPageLimit {
+		q.limit = maxPageLimit
+	}
+
+	switch {
+	case page.Before != "" && page.After != "":
+		return pageQuery{}, httpAPI.ErrBadCursor
+	case page.Before != "":
+		c, err := cursor.Decode(page.Before)
+		if err != nil {
+			return pageQuery{}, httpAPI.ErrBadCursor
+		}
+		q.cur = &c
+	case page.After != "":
+		c, err := cursor.Decode(page.After)
+		if err != nil {
+			return pageQuery{}, httpAPI.ErrBadCursor
+		}
+		q.cur = &c
+		q.cmp, q.order, q.after = ">", "ASC", true
+	}
+
+	return q, nil
+}
+
+// nextCursor вызывается с ключами строк в порядке выборки (limit+1 штук максимум).
+// Возвращает сколько строк оставить и курсор для следующей страницы.
+func (q pageQuery) nextCursor(keys []cursor.Cursor) (int, string) {
+	if len(keys) <= q.limit {
+		return len(keys), ""
+	}
+	return q.limit, cursor.Encode(keys[q.limit-1])
+}
+
+func reverse[T any](s []T) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
@@ -2,9 +2,13 @@ package postgresql
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"strings"
 	"time"
 
@@ -14,6 +18,7 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/cursor"
 	httpAPI "MicroserviceWebsocket/internal/server/http"
 )
 
@@ -44,6 +49,121 @@ func New(databaseURL string, log *slog.Logger) (*Storage, error) {
 
 // --- helpers ---
 
+// queryRower — общий интерфейс *sql.DB и *sql.Tx для точечных SELECT
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func activeModelID(ctx context.Context, q queryRower, name, version string) (int64, error) {
+	var modelID int64
+	err := q.QueryRowContext(ctx, `
+		SELECT id
+		FROM bot_models
+		WHERE name = $1 AND version = $2 AND is_active = TRUE
+	`, name, version).Scan(&modelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, httpAPI.ErrModelNotFound
+		}
+		return 0, err
+	}
+	return modelID, nil
+}
+
+const previewLen = 120
+
+func previewOf(content string) string {
+	r := []rune(strings.TrimSpace(content))
+	if len(r) > previewLen {
+		r = r[:previewLen]
+	}
+	return string(r)
+}
+
+// touchChat поднимает чат в списке и обновляет превью последнего сообщения
+func touchChat(ctx context.Context, e execer, chatUUID, content string) error {
+	_, err := e.ExecContext(ctx, `
+		UPDATE chats
+		SET updated_at = NOW(), last_message_at = NOW(), last_message_preview = $2
+		WHERE chat_uuid = $1::uuid
+	`, chatUUID, previewOf(content))
+	return err
+}
+
+func insertUserMessage(ctx context.Context, e execer, userID int64, chatUUID, messageUUID, content string) error {
+	res, err := e.ExecContext(ctx, `
+		INSERT INTO messages (message_uuid, chat_uuid, role, content, user_id)
+		VALUES ($1::uuid, $2::uuid, 'user', $3, $4)
+		ON CONFLICT (message_uuid) DO NOTHING
+	`, messageUUID, chatUUID, content, userID)
+	if err != nil {
+		return err
+	}
+	// повторная отправка того же uuid — чат не трогаем
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return nil
+	}
+	return touchChat(ctx, e, chatUUID, content)
+}
+
+func insertUsage(ctx context.Context, e execer, rec models.UsageRecord) error {
+	_, err := e.ExecContext(ctx, `
+		INSERT INTO usage (user_id, model_id, chat_uuid, message_uuid,
+		                   prompt_chars, response_chars, prompt_tokens, response_tokens)
+		VALUES ($1, $2, $3::uuid, $4::uuid, $5, $6, $7, $8)
+	`, rec.UserID, rec.ModelID, rec.ChatUUID, rec.MessageUUID,
+		rec.PromptChars, rec.ResponseChars, rec.PromptTokens, rec.ResponseTokens)
+	return err
+}
+
+// roleRank — owner > editor > viewer; у не-участника 0
+var roleRank = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleOwner:  3,
+}
+
+// chatAccess проверяет, что чат существует, не удалён и у userID в нём
+// роль не ниже need. Возвращает model_id чата.
+func chatAccess(ctx context.Context, q queryRower, userID int64, chatUUID, need string) (int64, error) {
+	var modelID int64
+	var isDeleted bool
+	var role sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT c.is_deleted, c.model_id, cm.role
+		FROM chats c
+		LEFT JOIN chat_members cm ON cm.chat_uuid = c.chat_uuid AND cm.user_id = $2
+		WHERE c.chat_uuid = $1::uuid
+	`, chatUUID, userID).Scan(&isDeleted, &modelID, &role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, httpAPI.ErrChatNotFound
+		}
+		return 0, err
+	}
+	if isDeleted {
+		return 0, httpAPI.ErrChatNotFound
+	}
+	if roleRank[role.String] < roleRank[need] {
+		return 0, httpAPI.ErrForbidden
+	}
+	return modelID, nil
+}
+
+// addOwner — запись владельца в chat_members при создании чата
+func addOwner(ctx context.Context, e execer, chatUUID string, userID int64) error {
+	_, err := e.ExecContext(ctx, `
+		INSERT INTO chat_members (chat_uuid, user_id, role)
+		VALUES ($1::uuid, $2, 'owner')
+		ON CONFLICT DO NOTHING
+	`, chatUUID, userID)
+	return err
+}
+
 func titleFromFirstMessage(s string) string {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -76,16 +196,8 @@ func (s *Storage) CreateChat(ctx context.Context, req models.CreateChatReq) (mod
 	defer func() { _ = tx.Rollback() }()
 
 	// 1) model_id
-	var modelID int64
-	err = tx.QueryRowContext(ctx, `
-		SELECT id
-		FROM bot_models
-		WHERE name = $1 AND version = $2 AND is_active = TRUE
-	`, req.ModelName, req.ModelVersion).Scan(&modelID)
+	modelID, err := activeModelID(ctx, tx, req.ModelName, req.ModelVersion)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.CreateChatResp{}, httpAPI.ErrModelNotFound
-		}
 		return models.CreateChatResp{}, err
 	}
 
@@ -112,6 +224,9 @@ func (s *Storage) CreateChat(ctx context.Context, req models.CreateChatReq) (mod
 	if err != nil {
 		return models.CreateChatResp{}, err
 	}
+	if err := addOwner(ctx, tx, chatUUID, req.UserID); err != nil {
+		return models.CreateChatResp{}, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return models.CreateChatResp{}, err
@@ -120,90 +235,339 @@ func (s *Storage) CreateChat(ctx context.Context, req models.CreateChatReq) (mod
 	return models.CreateChatResp{ChatUUID: chatUUID}, nil
 }
 
-func (s *Storage) ListChats(ctx context.Context, userID int64) (models.ListChatsResp, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT chat_uuid, title, model_id, updated_at
-		FROM chats
-		WHERE user_id = $1 AND is_deleted = FALSE
-		ORDER BY updated_at DESC
-	`, userID)
+// ImportChat вставляет чат со всеми сообщениями одной транзакцией.
+// Сообщения должны быть упорядочены так, что reply_to указывает на уже вставленные.
+func (s *Storage) ImportChat(ctx context.Context, userID int64, chat models.ImportChat) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// 1) model_id
+	modelID, err := activeModelID(ctx, tx, chat.ModelName, chat.ModelVersion)
+	if err != nil {
+		return err
+	}
+
+	// 2) chat: updated_at, last_message_at и превью — по последнему сообщению
+	title := chat.Title
+	if title == "" && len(chat.Messages) > 0 {
+		title = titleFromFirstMessage(chat.Messages[0].Content)
+	}
+	if title == "" {
+		title = "New chat"
+	}
+	updated := chat.CreatedAt
+	var preview sql.NullString
+	if n := len(chat.Messages); n > 0 {
+		updated = chat.Messages[n-1].CreatedAt
+		preview = sql.NullString{String: previewOf(chat.Messages[n-1].Content), Valid: true}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO chats (chat_uuid, user_id, model_id, title, created_at, updated_at, last_message_at, last_message_preview)
+		VALUES ($1::uuid, $2, $3, $4, $5, $6, $6, $7)
+		ON CONFLICT (chat_uuid) DO NOTHING
+	`, chat.ChatUUID, userID, modelID, title, chat.CreatedAt, updated, preview)
+	if err != nil {
+		return err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return httpAPI.ErrChatExists
+	}
+	if err := addOwner(ctx, tx, chat.ChatUUID, userID); err != nil {
+		return err
+	}
+
+	// 3) messages (у ответов бота model_id чата, у своих — автор)
+	for _, m := range chat.Messages {
+		var msgModel, author sql.NullInt64
+		if m.Role == "bot" {
+			msgModel = sql.NullInt64{Int64: modelID, Valid: true}
+		} else {
+			author = sql.NullInt64{Int64: userID, Valid: true}
+		}
+		var reply sql.NullString
+		if m.ReplyToMessageID != "" {
+			reply = sql.NullString{String: m.ReplyToMessageID, Valid: true}
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO messages (message_uuid, chat_uuid, role, content, model_id, reply_to_message_id, created_at, updated_at, user_id)
+			VALUES ($1::uuid, $2::uuid, $3, $4, $5, $6::uuid, $7, $7, $8)
+		`, m.MessageUUID, chat.ChatUUID, m.Role, m.Content, msgModel, reply, m.CreatedAt, author)
+		if err != nil {
+			// тот же экспорт, импортированный повторно под другим chat_uuid
+			if isUniqueViolation(err) {
+				return httpAPI.ErrMessageExists
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListChats отдаёт свои и общие чаты: закреплённые первыми, дальше от новых к старым.
+// next_cursor передаётся обратно в том же параметре (before/after), которым была запрошена страница
+func (s *Storage) ListChats(ctx context.Context, userID int64, filter models.ChatsFilter, page models.PageReq) (models.ListChatsResp, error) {
+	pq, err := newPageQuery(page)
+	if err != nil {
+		return models.ListChatsResp{}, err
+	}
+
+	args := []any{userID, pq.limit + 1}
+	where := "TRUE"
+	if filter.Deleted {
+		// корзина: архивность не важна, видна только владельцу
+		where += " AND c.is_deleted = TRUE AND cm.role = 'owner'"
+	} else {
+		args = append(args, filter.Archived)
+		where += " AND c.is_deleted = FALSE AND cm.is_archived = $3"
+	}
+	if filter.Pinned != nil {
+		args = append(args, *filter.Pinned)
+		where += fmt.Sprintf(" AND cm.is_pinned = $%d", len(args))
+	}
+	if filter.FolderID != nil {
+		args = append(args, *filter.FolderID)
+		where += fmt.Sprintf(" AND fc.folder_id = $%d", len(args))
+	}
+	if filter.TagID != nil {
+		args = append(args, *filter.TagID)
+		where += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM chat_tags ct JOIN tags t ON t.id = ct.tag_id
+			WHERE ct.chat_uuid = c.chat_uuid AND t.user_id = $1 AND ct.tag_id = $%d)`, len(args))
+	}
+	if pq.cur != nil {
+		args = append(args, pq.cur.Pinned, pq.cur.Time, pq.cur.ID)
+		n := len(args)
+		where += fmt.Sprintf(" AND (cm.is_pinned, c.last_message_at, c.chat_uuid) %s ($%d, $%d, $%d::uuid)", pq.cmp, n-2, n-1, n)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT c.chat_uuid, c.title, c.model_id, c.updated_at, c.last_message_at, COALESCE(c.last_message_preview, ''),
+		       cm.is_pinned, cm.is_archived, c.deleted_at, cm.role, fc.folder_id,
+		       COALESCE((
+		           SELECT json_agg(ct.tag_id ORDER BY ct.tag_id)
+		           FROM chat_tags ct JOIN tags t ON t.id = ct.tag_id
+		           WHERE ct.chat_uuid = c.chat_uuid AND t.user_id = $1
+		       ), '[]')
+		FROM chats c
+		JOIN chat_members cm ON cm.chat_uuid = c.chat_uuid AND cm.user_id = $1
+		LEFT JOIN folder_chats fc ON fc.chat_uuid = c.chat_uuid AND fc.user_id = $1
+		WHERE %s
+		ORDER BY cm.is_pinned %s, c.last_message_at %s, c.chat_uuid %s
+		LIMIT $2
+	`, where, pq.order, pq.order, pq.order), args...)
 	if err != nil {
 		return models.ListChatsResp{}, err
 	}
 	defer rows.Close()
 
 	resp := models.ListChatsResp{Items: make([]models.ChatItem, 0, 16)}
+	keys := make([]cursor.Cursor, 0, 16)
 	for rows.Next() {
 		var it models.ChatItem
-		var updated time.Time
-		if err := rows.Scan(&it.ID, &it.Title, &it.ModelID, &updated); err != nil {
+		var updated, lastMessage time.Time
+		var deleted sql.NullTime
+		var folder sql.NullInt64
+		var tagIDs []byte
+		if err := rows.Scan(&it.ID, &it.Title, &it.ModelID, &updated, &lastMessage, &it.LastMessage, &it.Pinned, &it.Archived, &deleted, &it.Role, &folder, &tagIDs); err != nil {
+			return models.ListChatsResp{}, err
+		}
+		if folder.Valid {
+			it.FolderID = &folder.Int64
+		}
+		if err := json.Unmarshal(tagIDs, &it.TagIDs); err != nil {
 			return models.ListChatsResp{}, err
 		}
 		it.UpdatedAt = updated.UTC().Format(time.RFC3339)
+		it.LastMessageAt = lastMessage.UTC().Format(time.RFC3339)
+		if deleted.Valid {
+			it.DeletedAt = deleted.Time.UTC().Format(time.RFC3339)
+		}
 		resp.Items = append(resp.Items, it)
+		keys = append(keys, cursor.Cursor{Time: lastMessage, ID: it.ID, Pinned: it.Pinned})
 	}
 	if err := rows.Err(); err != nil {
 		return models.ListChatsResp{}, err
 	}
 
+	n, next := pq.nextCursor(keys)
+	resp.Items, resp.NextCursor = resp.Items[:n], next
+	// after выбирали по возрастанию — возвращаем к порядку "новые сверху"
+	if pq.after {
+		reverse(resp.Items)
+	}
+
 	return resp, nil
 }
 
-func (s *Storage) ListMessages(ctx context.Context, userID int64, chatUUID string) (models.ListMessagesResp, error) {
-	// 1) check chat exists and belongs
-	var owner int64
-	var isDeleted bool
-	err := s.db.QueryRowContext(ctx, `
-		SELECT user_id, is_deleted
+// UpdateChat меняет title (только владелец) и pinned/archived — личные флаги
+// участника в chat_members, доступные любой роли
+func (s *Storage) UpdateChat(ctx context.Context, chatUUID string, req models.UpdateChatReq) (models.ChatItem, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return models.ChatItem{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	need := models.RoleViewer
+	if req.Title != nil {
+		need = models.RoleOwner
+	}
+	if _, err := chatAccess(ctx, tx, req.UserID, chatUUID, need); err != nil {
+		return models.ChatItem{}, err
+	}
+
+	if req.Title != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE chats SET title = $2 WHERE chat_uuid = $1::uuid
+		`, chatUUID, *req.Title); err != nil {
+			return models.ChatItem{}, err
+		}
+	}
+
+	var it models.ChatItem
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE chat_members
+		SET is_pinned   = COALESCE($3, is_pinned),
+		    is_archived = COALESCE($4, is_archived)
+		WHERE chat_uuid = $1::uuid AND user_id = $2
+		RETURNING role, is_pinned, is_archived
+	`, chatUUID, req.UserID, req.Pinned, req.Archived).Scan(&it.Role, &it.Pinned, &it.Archived); err != nil {
+		return models.ChatItem{}, err
+	}
+
+	var updated, lastMessage time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT chat_uuid, title, model_id, updated_at, last_message_at, COALESCE(last_message_preview, '')
 		FROM chats
 		WHERE chat_uuid = $1::uuid
-	`, chatUUID).Scan(&owner, &isDeleted)
+	`, chatUUID).Scan(&it.ID, &it.Title, &it.ModelID, &updated, &lastMessage, &it.LastMessage)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.ListMessagesResp{}, httpAPI.ErrChatNotFound
-		}
-		return models.ListMessagesResp{}, err
+		return models.ChatItem{}, err
 	}
-	if isDeleted {
-		return models.ListMessagesResp{}, httpAPI.ErrChatNotFound
+	it.UpdatedAt = updated.UTC().Format(time.RFC3339)
+	it.LastMessageAt = lastMessage.UTC().Format(time.RFC3339)
+
+	if err := tx.Commit(); err != nil {
+		return models.ChatItem{}, err
 	}
-	if owner != userID {
-		return models.ListMessagesResp{}, httpAPI.ErrForbidden
+	return it, nil
+}
+
+// messageSelect — общие колонки сообщений для ListMessages и экспорта.
+// $1 = chat_uuid, $2 = user_id (чей feedback показываем)
+const messageSelect = `
+	SELECT m.message_uuid, m.role, m.user_id, m.content, m.created_at, m.reply_to_message_id, f.is_positive,
+	       m.model_id, bm.name, bm.version
+	FROM messages m
+	LEFT JOIN message_feedbacks f ON f.message_uuid = m.message_uuid AND f.user_id = $2
+	LEFT JOIN bot_models bm ON bm.id = m.model_id AND m.role = 'bot'
+`
+
+func scanMessage(rows *sql.Rows) (models.MessageItem, time.Time, error) {
+	var it models.MessageItem
+	var created time.Time
+	var reply sql.NullString
+	var author sql.NullInt64
+	var feedback sql.NullBool
+	var modelID sql.NullInt64
+	var modelName, modelVersion sql.NullString
+	if err := rows.Scan(&it.ID, &it.Role, &author, &it.Content, &created, &reply, &feedback,
+		&modelID, &modelName, &modelVersion); err != nil {
+		return models.MessageItem{}, time.Time{}, err
+	}
+	it.CreatedAt = created.UTC().Format(time.RFC3339)
+	it.UserID = author.Int64
+	if reply.Valid {
+		it.ReplyToMessageID = reply.String
+	}
+	if feedback.Valid {
+		it.Feedback = &feedback.Bool
+	}
+	if modelName.Valid {
+		it.ModelID, it.ModelName, it.ModelVersion = modelID.Int64, modelName.String, modelVersion.String
+	}
+	return it, created, nil
+}
+
+// ListMessages отдаёт сообщения по возрастанию времени. Без курсора — последняя
+// страница переписки, before листает в прошлое, after — к новым сообщениям
+func (s *Storage) ListMessages(ctx context.Context, userID int64, chatUUID string, page models.PageReq) (models.ListMessagesResp, error) {
+	// 1) check chat exists and user is a member
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return models.ListMessagesResp{}, err
 	}
 
 	// 2) list messages
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT message_uuid, role, content, created_at, reply_to_message_id
-		FROM messages
-		WHERE chat_uuid = $1::uuid AND is_deleted = FALSE
-		ORDER BY created_at ASC
-	`, chatUUID)
+	return s.listMessages(ctx, chatUUID, sql.NullInt64{Int64: userID, Valid: true}, page, nil)
+}
+
+// listMessages — выборка страницы без проверки доступа.
+// viewer — чей feedback показывать (NULL = ничей), until — верхняя граница created_at.
+func (s *Storage) listMessages(
+	ctx context.Context,
+	chatUUID string,
+	viewer sql.NullInt64,
+	page models.PageReq,
+	until *time.Time,
+) (models.ListMessagesResp, error) {
+	pq, err := newPageQuery(page)
+	if err != nil {
+		return models.ListMessagesResp{}, err
+	}
+
+	args := []any{chatUUID, viewer, pq.limit + 1}
+	where := "m.chat_uuid = $1::uuid AND m.is_deleted = FALSE"
+	if until != nil {
+		args = append(args, *until)
+		where += fmt.Sprintf(" AND m.created_at <= $%d", len(args))
+	}
+	if pq.cur != nil {
+		args = append(args, pq.cur.Time, pq.cur.ID)
+		n := len(args)
+		where += fmt.Sprintf(" AND (m.created_at, m.message_uuid) %s ($%d, $%d::uuid)", pq.cmp, n-1, n)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(messageSelect+`
+		WHERE %s
+		ORDER BY m.created_at %s, m.message_uuid %s
+		LIMIT $3
+	`, where, pq.order, pq.order), args...)
 	if err != nil {
 		return models.ListMessagesResp{}, err
 	}
 	defer rows.Close()
 
 	resp := models.ListMessagesResp{ChatID: chatUUID, Items: make([]models.MessageItem, 0, 64)}
+	keys := make([]cursor.Cursor, 0, 64)
 	for rows.Next() {
-		var it models.MessageItem
-		var created time.Time
-		var reply sql.NullString
-		if err := rows.Scan(&it.ID, &it.Role, &it.Content, &created, &reply); err != nil {
+		it, created, err := scanMessage(rows)
+		if err != nil {
 			return models.ListMessagesResp{}, err
 		}
-		it.CreatedAt = created.UTC().Format(time.RFC3339)
-		if reply.Valid {
-			it.ReplyToMessageID = reply.String
-		}
 		resp.Items = append(resp.Items, it)
+		keys = append(keys, cursor.Cursor{Time: created, ID: it.ID})
 	}
 	if err := rows.Err(); err != nil {
 		return models.ListMessagesResp{}, err
 	}
 
+	n, next := pq.nextCursor(keys)
+	resp.Items, resp.NextCursor = resp.Items[:n], next
+	// before/без курсора выбирали от новых к старым — в ответе хронологический порядок
+	if !pq.after {
+		reverse(resp.Items)
+	}
+
 	return resp, nil
 }
 
+// DeleteChat отправляет чат в корзину; только владелец
 func (s *Storage) DeleteChat(ctx context.Context, userID int64, chatUUID string) error {
 	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
@@ -211,40 +575,23 @@ func (s *Storage) DeleteChat(ctx context.Context, userID int64, chatUUID string)
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	// 1) mark chat deleted (only if belongs and not deleted)
-	res, err := tx.ExecContext(ctx, `
+	// 1) mark chat deleted (only owner, not deleted)
+	if _, err := chatAccess(ctx, tx, userID, chatUUID, models.RoleOwner); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
 		UPDATE chats
 		SET is_deleted = TRUE, deleted_at = NOW(), updated_at = NOW()
-		WHERE chat_uuid = $1::uuid AND user_id = $2 AND is_deleted = FALSE
-	`, chatUUID, userID)
+		WHERE chat_uuid = $1::uuid
+	`, chatUUID)
 	if err != nil {
 		return err
 	}
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		var owner int64
-		var isDel bool
-		err := tx.QueryRowContext(ctx, `
-			SELECT user_id, is_deleted
-			FROM chats
-			WHERE chat_uuid = $1::uuid
-		`, chatUUID).Scan(&owner, &isDel)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return httpAPI.ErrChatNotFound
-			}
-			return err
-		}
-		if owner != userID {
-			return httpAPI.ErrForbidden
-		}
-		return httpAPI.ErrChatNotFound
-	}
 
-	// 2) mark all messages deleted
+	// 2) mark all messages deleted (помечаем, что вместе с чатом — для restore)
 	_, err = tx.ExecContext(ctx, `
 		UPDATE messages
-		SET is_deleted = TRUE, deleted_at = NOW(), updated_at = NOW()
+		SET is_deleted = TRUE, deleted_at = NOW(), updated_at = NOW(), deleted_with_chat = TRUE
 		WHERE chat_uuid = $1::uuid AND is_deleted = FALSE
 	`, chatUUID)
 	if err != nil {
@@ -254,73 +601,1314 @@ func (s *Storage) DeleteChat(ctx context.Context, userID int64, chatUUID string)
 	return tx.Commit()
 }
 
-func (s *Storage) SetFeedback(ctx context.Context, messageUUID string, userID int64, isPositive bool) (models.FeedbackResp, error) {
-	// 1) message exists, role=bot, not deleted, and belongs to user's chat
-	var role string
-	var isDeleted bool
-	var chatOwner int64
+// RestoreChat возвращает чат из корзины вместе с сообщениями, удалёнными
+// в составе чата; удалённые ранее по отдельности остаются удалёнными
+func (s *Storage) RestoreChat(ctx context.Context, userID int64, chatUUID string) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	err := s.db.QueryRowContext(ctx, `
-		SELECT m.role, m.is_deleted, c.user_id
-		FROM messages m
-		JOIN chats c ON c.chat_uuid = m.chat_uuid
-		WHERE m.message_uuid = $1::uuid
-	`, messageUUID).Scan(&role, &isDeleted, &chatOwner)
+	// 1) chat exists, belongs and is in trash
+	var owner int64
+	var isDeleted bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, is_deleted
+		FROM chats
+		WHERE chat_uuid = $1::uuid
+		FOR UPDATE
+	`, chatUUID).Scan(&owner, &isDeleted)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.FeedbackResp{}, httpAPI.ErrMessageNotFound
+			return httpAPI.ErrChatNotFound
 		}
-		return models.FeedbackResp{}, err
+		return err
 	}
-	if isDeleted {
-		return models.FeedbackResp{}, httpAPI.ErrMessageNotFound
+	if owner != userID {
+		return httpAPI.ErrForbidden
 	}
-	if chatOwner != userID {
-		return models.FeedbackResp{}, httpAPI.ErrForbidden
+	if !isDeleted {
+		return httpAPI.ErrNotDeleted
 	}
-	if role != "bot" {
-		return models.FeedbackResp{}, httpAPI.ErrNotBotMessage
+
+	// 2) restore chat
+	_, err = tx.ExecContext(ctx, `
+		UPDATE chats
+		SET is_deleted = FALSE, deleted_at = NULL
+		WHERE chat_uuid = $1::uuid
+	`, chatUUID)
+	if err != nil {
+		return err
 	}
 
-	// 2) model_id берём из чата (чтобы не доверять фронту)
-	var modelID int64
-	err = s.db.QueryRowContext(ctx, `
-		SELECT c.model_id
-		FROM messages m
-		JOIN chats c ON c.chat_uuid = m.chat_uuid
-		WHERE m.message_uuid = $1::uuid
-	`, messageUUID).Scan(&modelID)
+	// 3) restore messages deleted together with chat
+	_, err = tx.ExecContext(ctx, `
+		UPDATE messages
+		SET is_deleted = FALSE, deleted_at = NULL, deleted_with_chat = FALSE
+		WHERE chat_uuid = $1::uuid AND deleted_with_chat = TRUE
+	`, chatUUID)
 	if err != nil {
-		return models.FeedbackResp{}, err
+		return err
 	}
 
-	// 3) upsert feedback
-	_, err = s.db.ExecContext(ctx, `
-		INSERT INTO message_feedbacks (message_uuid, user_id, model_id, is_positive)
-		VALUES ($1::uuid, $2, $3, $4)
-		ON CONFLICT (user_id, message_uuid)
-		DO UPDATE SET is_positive = EXCLUDED.is_positive, updated_at = NOW()
-	`, messageUUID, userID, modelID, isPositive)
+	return tx.Commit()
+}
+
+// ChatInfo — заголовок и модель чата (для экспорта); доступ как в ListMessages
+func (s *Storage) ChatInfo(ctx context.Context, userID int64, chatUUID string) (models.ChatInfo, error) {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return models.ChatInfo{}, err
+	}
+
+	var info models.ChatInfo
+	var created, updated time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.chat_uuid, c.title, c.model_id, b.name, b.version, c.created_at, c.updated_at
+		FROM chats c
+		JOIN bot_models b ON b.id = c.model_id
+		WHERE c.chat_uuid = $1::uuid
+	`, chatUUID).Scan(&info.ID, &info.Title, &info.ModelID, &info.ModelName, &info.ModelVersion, &created, &updated)
 	if err != nil {
-		return models.FeedbackResp{}, err
+		return models.ChatInfo{}, err
 	}
+	info.CreatedAt = created.UTC().Format(time.RFC3339)
+	info.UpdatedAt = updated.UTC().Format(time.RFC3339)
 
-	return models.FeedbackResp{MessageID: messageUUID, IsPositive: isPositive}, nil
+	return info, nil
 }
 
-func (s *Storage) InsertUserMessage(ctx context.Context, chatUUID, messageUUID, content string) error {
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO messages (message_uuid, chat_uuid, role, content)
-		VALUES ($1::uuid, $2::uuid, 'user', $3)
-		ON CONFLICT (message_uuid) DO NOTHING
-	`, messageUUID, chatUUID, content)
-	return err
+// --- members ---
+
+// ChatMemberIDs — user_id всех участников чата (для ws-уведомлений; доступ уже проверен)
+func (s *Storage) ChatMemberIDs(ctx context.Context, chatUUID string) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id FROM chat_members WHERE chat_uuid = $1::uuid
+	`, chatUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0, 4)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
-func (s *Storage) InsertBotMessage(ctx context.Context, chatUUID, messageUUID, content, replyToUUID string) error {
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO messages (message_uuid, chat_uuid, role, content, reply_to_message_id)
-		VALUES ($1::uuid, $2::uuid, 'bot', $3, $4::uuid)
-	`, messageUUID, chatUUID, content, replyToUUID)
-	return err
+// ListMembers — участники чата; видно любому участнику
+func (s *Storage) ListMembers(ctx context.Context, userID int64, chatUUID string) (models.ListMembersResp, error) {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return models.ListMembersResp{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, role, invited_by, created_at
+		FROM chat_members
+		WHERE chat_uuid = $1::uuid
+		ORDER BY created_at, user_id
+	`, chatUUID)
+	if err != nil {
+		return models.ListMembersResp{}, err
+	}
+	defer rows.Close()
+
+	resp := models.ListMembersResp{ChatID: chatUUID, Items: make([]models.ChatMember, 0, 4)}
+	for rows.Next() {
+		var m models.ChatMember
+		var invitedBy sql.NullInt64
+		var created time.Time
+		if err := rows.Scan(&m.UserID, &m.Role, &invitedBy, &created); err != nil {
+			return models.ListMembersResp{}, err
+		}
+		m.InvitedBy = invitedBy.Int64
+		m.CreatedAt = created.UTC().Format(time.RFC3339)
+		resp.Items = append(resp.Items, m)
+	}
+	if err := rows.Err(); err != nil {
+		return models.ListMembersResp{}, err
+	}
+
+	return resp, nil
+}
+
+// AddMember приглашает пользователя в чат или меняет его роль; только владелец.
+// Роль владельца так не передаётся.
+func (s *Storage) AddMember(ctx context.Context, req models.AddMemberReq, chatUUID string) (models.ChatMember, error) {
+	if _, err := chatAccess(ctx, s.db, req.UserID, chatUUID, models.RoleOwner); err != nil {
+		return models.ChatMember{}, err
+	}
+	if req.MemberID == req.UserID {
+		return models.ChatMember{}, httpAPI.ErrForbidden
+	}
+
+	m := models.ChatMember{UserID: req.MemberID, InvitedBy: req.UserID}
+	var created time.Time
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO chat_members (chat_uuid, user_id, role, invited_by)
+		VALUES ($1::uuid, $2, $3, $4)
+		ON CONFLICT (chat_uuid, user_id)
+		DO UPDATE SET role = EXCLUDED.role
+		WHERE chat_members.role <> 'owner'
+		RETURNING role, created_at
+	`, chatUUID, req.MemberID, req.Role, req.UserID).Scan(&m.Role, &created)
+	if err != nil {
+		// конфликт с владельцем: DO UPDATE ничего не вернул
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ChatMember{}, httpAPI.ErrForbidden
+		}
+		return models.ChatMember{}, err
+	}
+	m.CreatedAt = created.UTC().Format(time.RFC3339)
+
+	return m, nil
+}
+
+// RemoveMember исключает участника (владелец) или выход из чата (сам участник).
+// Владельца удалить нельзя — только удалить чат.
+func (s *Storage) RemoveMember(ctx context.Context, userID int64, chatUUID string, memberID int64) error {
+	need := models.RoleOwner
+	if memberID == userID {
+		need = models.RoleViewer
+	}
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, need); err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM chat_members
+		WHERE chat_uuid = $1::uuid AND user_id = $2 AND role <> 'owner'
+	`, chatUUID, memberID)
+	if err != nil {
+		return err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		if memberID == userID {
+			return httpAPI.ErrForbidden
+		}
+		return httpAPI.ErrMemberNotFound
+	}
+	return nil
+}
+
+// --- shares ---
+
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateShare делает снимок чата на текущий момент и выдаёт публичный токен
+func (s *Storage) CreateShare(ctx context.Context, userID int64, chatUUID string) (models.ShareResp, error) {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleOwner); err != nil {
+		return models.ShareResp{}, err
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return models.ShareResp{}, err
+	}
+
+	var until time.Time
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO chat_shares (token, chat_uuid, user_id, shared_until)
+		VALUES ($1, $2::uuid, $3, NOW())
+		RETURNING shared_until
+	`, token, chatUUID, userID).Scan(&until)
+	if err != nil {
+		return models.ShareResp{}, err
+	}
+
+	return models.ShareResp{
+		Token:       token,
+		ChatID:      chatUUID,
+		SharedUntil: until.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// RevokeShares отзывает все активные ссылки на чат
+func (s *Storage) RevokeShares(ctx context.Context, userID int64, chatUUID string) error {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE chat_shares
+		SET revoked_at = NOW()
+		WHERE chat_uuid = $1::uuid AND revoked_at IS NULL
+	`, chatUUID)
+	return err
+}
+
+// SharedChat — снимок чата по токену. Владелец не проверяется,
+// но удалённые чат/сообщения и отозванные ссылки не показываются.
+func (s *Storage) SharedChat(ctx context.Context, token string, page models.PageReq) (models.SharedChatResp, error) {
+	var chatUUID, title string
+	var until time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.chat_uuid, c.title, sh.shared_until
+		FROM chat_shares sh
+		JOIN chats c ON c.chat_uuid = sh.chat_uuid
+		WHERE sh.token = $1 AND sh.revoked_at IS NULL AND c.is_deleted = FALSE
+	`, token).Scan(&chatUUID, &title, &until)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SharedChatResp{}, httpAPI.ErrShareNotFound
+		}
+		return models.SharedChatResp{}, err
+	}
+
+	msgs, err := s.listMessages(ctx, chatUUID, sql.NullInt64{}, page, &until)
+	if err != nil {
+		return models.SharedChatResp{}, err
+	}
+
+	return models.SharedChatResp{
+		Title:       title,
+		SharedUntil: until.UTC().Format(time.RFC3339),
+		Items:       msgs.Items,
+		NextCursor:  msgs.NextCursor,
+	}, nil
+}
+
+// EachMessage построчно отдаёт все сообщения чата в хронологическом порядке,
+// не собирая их в память (для экспорта)
+func (s *Storage) EachMessage(ctx context.Context, userID int64, chatUUID string, fn func(models.MessageItem) error) error {
+	if _, err := chatAccess(ctx, s.db, userID, chatUUID, models.RoleViewer); err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, messageSelect+`
+		WHERE m.chat_uuid = $1::uuid AND m.is_deleted = FALSE
+		ORDER BY m.created_at, m.message_uuid
+	`, chatUUID, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		it, _, err := scanMessage(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(it); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DeleteMessage скрывает сообщение; для user message скрываются и ответы бота на него.
+// Удалять может автор (editor+; для ответа бота — автор промпта) или владелец чата.
+func (s *Storage) DeleteMessage(ctx context.Context, messageUUID string, userID int64) (models.DeleteMessageResp, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return models.DeleteMessageResp{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// 1) message exists, not deleted, and user may write to the chat.
+	// Автор: у старых сообщений без user_id — создатель чата
+	var role, chatUUID string
+	var isDeleted bool
+	var authorID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT m.role, m.is_deleted, m.chat_uuid, COALESCE(m.user_id, p.user_id, c.user_id)
+		FROM messages m
+		JOIN chats c ON c.chat_uuid = m.chat_uuid
+		LEFT JOIN messages p ON p.message_uuid = m.reply_to_message_id
+		WHERE m.message_uuid = $1::uuid
+	`, messageUUID).Scan(&role, &isDeleted, &chatUUID, &authorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DeleteMessageResp{}, httpAPI.ErrMessageNotFound
+		}
+		return models.DeleteMessageResp{}, err
+	}
+	if isDeleted {
+		return models.DeleteMessageResp{}, httpAPI.ErrMessageNotFound
+	}
+	if _, err := chatAccess(ctx, tx, userID, chatUUID, models.RoleEditor); err != nil {
+		if errors.Is(err, httpAPI.ErrChatNotFound) {
+			return models.DeleteMessageResp{}, httpAPI.ErrMessageNotFound
+		}
+		return models.DeleteMessageResp{}, err
+	}
+	// чужие сообщения в общем чате скрывает только владелец (модерация)
+	if authorID != userID {
+		if _, err := chatAccess(ctx, tx, userID, chatUUID, models.RoleOwner); err != nil {
+			return models.DeleteMessageResp{}, err
+		}
+	}
+
+	// 2) mark message (and bot replies to it) deleted
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE messages
+		SET is_deleted = TRUE, deleted_at = NOW()
+		WHERE is_deleted = FALSE
+		  AND (message_uuid = $1::uuid OR ($2 = 'user' AND reply_to_message_id = $1::uuid))
+		RETURNING message_uuid
+	`, messageUUID, role)
+	if err != nil {
+		return models.DeleteMessageResp{}, err
+	}
+	resp := models.DeleteMessageResp{ChatID: chatUUID, DeletedIDs: make([]string, 0, 2)}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return models.DeleteMessageResp{}, err
+		}
+		resp.DeletedIDs = append(resp.DeletedIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.DeleteMessageResp{}, err
+	}
+
+	// 3) превью чата не должно показывать скрытый текст
+	_, err = tx.ExecContext(ctx, `
+		UPDATE chats
+		SET last_message_preview = (
+			SELECT LEFT(content, $2)
+			FROM messages
+			WHERE chat_uuid = $1::uuid AND is_deleted = FALSE
+			ORDER BY created_at DESC
+			LIMIT 1
+		)
+		WHERE chat_uuid = $1::uuid
+	`, chatUUID, previewLen)
+	if err != nil {
+		return models.DeleteMessageResp{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.DeleteMessageResp{}, err
+	}
+	return resp, nil
+}
+
+// feedbackTarget проверяет, что сообщение от бота, не удалено и пользователь — участник чата.
+// Возвращает model_id модели, написавшей ответ (чтобы не доверять фронту): модель чата
+// могли сменить, а в arena соседние ответы — от разных моделей.
+// Для старых строк без messages.model_id — модель чата.
+func feedbackTarget(ctx context.Context, q queryRower, messageUUID string, userID int64) (int64, error) {
+	var role, chatUUID string
+	var isDeleted bool
+	var modelID int64
+
+	err := q.QueryRowContext(ctx, `
+		SELECT m.role, m.is_deleted, m.chat_uuid, COALESCE(m.model_id, c.model_id)
+		FROM messages m
+		JOIN chats c ON c.chat_uuid = m.chat_uuid
+		WHERE m.message_uuid = $1::uuid
+	`, messageUUID).Scan(&role, &isDeleted, &chatUUID, &modelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, httpAPI.ErrMessageNotFound
+		}
+		return 0, err
+	}
+	if isDeleted {
+		return 0, httpAPI.ErrMessageNotFound
+	}
+
+	if _, err := chatAccess(ctx, q, userID, chatUUID, models.RoleViewer); err != nil {
+		if errors.Is(err, httpAPI.ErrChatNotFound) {
+			return 0, httpAPI.ErrMessageNotFound
+		}
+		return 0, err
+	}
+	if role != "bot" {
+		return 0, httpAPI.ErrNotBotMessage
+	}
+	return modelID, nil
+}
+
+// SetFeedback — upsert оценки; reason/comment перезаписываются вместе с is_positive
+func (s *Storage) SetFeedback(ctx context.Context, messageUUID string, req models.FeedbackReq) (models.FeedbackResp, error) {
+	// 1) message exists, role=bot, not deleted; оценивать может любой участник чата
+	modelID, err := feedbackTarget(ctx, s.db, messageUUID, req.UserID)
+	if err != nil {
+		return models.FeedbackResp{}, err
+	}
+
+	// 2) upsert feedback (пустые reason/comment храним как NULL)
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO message_feedbacks (message_uuid, user_id, model_id, is_positive, reason, comment)
+		VALUES ($1::uuid, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+		ON CONFLICT (user_id, message_uuid) WHERE user_id <> 0
+		DO UPDATE SET is_positive = EXCLUDED.is_positive,
+		              reason      = EXCLUDED.reason,
+		              comment     = EXCLUDED.comment,
+		              updated_at  = NOW()
+	`, messageUUID, req.UserID, modelID, req.IsPositive, req.Reason, req.Comment)
+	if err != nil {
+		return models.FeedbackResp{}, err
+	}
+
+	return models.FeedbackResp{
+		MessageID:  messageUUID,
+		IsPositive: req.IsPositive,
+		Reason:     req.Reason,
+		Comment:    req.Comment,
+	}, nil
+}
+
+// DeleteFeedback удаляет оценку пользователя целиком
+func (s *Storage) DeleteFeedback(ctx context.Context, messageUUID string, userID int64) error {
+	if _, err := feedbackTarget(ctx, s.db, messageUUID, userID); err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM message_feedbacks
+		WHERE message_uuid = $1::uuid AND user_id = $2
+	`, messageUUID, userID)
+	if err != nil {
+		return err
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return httpAPI.ErrFeedbackNotFound
+	}
+	return nil
+}
+
+// InsertUserMessage сохраняет сообщение и обновляет updated_at/превью чата атомарно
+func (s *Storage) InsertUserMessage(ctx context.Context, userID int64, chatUUID, messageUUID, content string) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertUserMessage(ctx, tx, userID, chatUUID, messageUUID, content); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveBotTurn записывает ответ бота (с model_id и latency), обновляет чат
+// и учитывает usage — всё в одной транзакции
+func (s *Storage) SaveBotTurn(ctx context.Context, turn models.BotTurn) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (message_uuid, chat_uuid, role, content, model_id, reply_to_message_id, latency_ms)
+		VALUES ($1::uuid, $2::uuid, 'bot', $3, $4, $5::uuid, $6)
+	`, turn.MessageUUID, turn.ChatUUID, turn.Content, turn.ModelID, turn.ReplyToUUID, turn.LatencyMs)
+	if err != nil {
+		return err
+	}
+
+	if err := touchChat(ctx, tx, turn.ChatUUID, turn.Content); err != nil {
+		return err
+	}
+
+	if err := insertUsage(ctx, tx, turn.Usage); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// --- methods used by WS handler ---
+
+// CheckChatAccess — может ли пользователь писать в чат (editor и выше); возвращает model_id чата
+func (s *Storage) CheckChatAccess(ctx context.Context, userID int64, chatUUID string) (int64, error) {
+	return chatAccess(ctx, s.db, userID, chatUUID, models.RoleEditor)
+}
+
+func (s *Storage) ActiveModelID(ctx context.Context, name, version string) (int64, error) {
+	return activeModelID(ctx, s.db, name, version)
+}
+
+// CreateChatWithUserMessage создаёт чат по первому сообщению (title из текста)
+// и сохраняет само сообщение в одной транзакции.
+// created=false, если чат успел создать параллельный запрос того же пользователя.
+func (s *Storage) CreateChatWithUserMessage(
+	ctx context.Context,
+	userID, modelID int64,
+	chatUUID, messageUUID, content string,
+) (title string, created bool, err error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return "", false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	title = titleFromFirstMessage(content)
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO chats (chat_uuid, user_id, model_id, title)
+		VALUES ($1::uuid, $2, $3, $4)
+		ON CONFLICT (chat_uuid) DO NOTHING
+	`, chatUUID, userID, modelID, title)
+	if err != nil {
+		return "", false, err
+	}
+	aff, _ := res.RowsAffected()
+	created = aff > 0
+	if created {
+		if err := addOwner(ctx, tx, chatUUID, userID); err != nil {
+			return "", false, err
+		}
+	} else {
+		// чат с таким uuid уже есть: удалён, чужой или создан параллельно
+		if _, err := chatAccess(ctx, tx, userID, chatUUID, models.RoleEditor); err != nil {
+			return "", false, err
+		}
+	}
+
+	if err := insertUserMessage(ctx, tx, userID, chatUUID, messageUUID, content); err != nil {
+		return "", false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", false, err
+	}
+	return title, created, nil
+}
+
+// --- usage & quotas ---
+
+// CheckQuota проверяет, что с учётом promptTokens пользователь
+// не выйдет за дневную/месячную квоту модели
+func (s *Storage) CheckQuota(ctx context.Context, userID, modelID int64, promptTokens int) error {
+	var dailyQuota, monthlyQuota sql.NullInt64
+	var dailyUsed, monthlyUsed int64
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT b.daily_token_quota,
+		       b.monthly_token_quota,
+		       COALESCE(SUM(u.prompt_tokens + u.response_tokens)
+		                FILTER (WHERE u.created_at >= date_trunc('day', NOW())), 0),
+		       COALESCE(SUM(u.prompt_tokens + u.response_tokens), 0)
+		FROM bot_models b
+		LEFT JOIN usage u
+		       ON u.model_id = b.id
+		      AND u.user_id = $2
+		      AND u.created_at >= date_trunc('month', NOW())
+		WHERE b.id = $1
+		GROUP BY b.id
+	`, modelID, userID).Scan(&dailyQuota, &monthlyQuota, &dailyUsed, &monthlyUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return httpAPI.ErrModelNotFound
+		}
+		return err
+	}
+
+	next := int64(promptTokens)
+	if dailyQuota.Valid && dailyUsed+next > dailyQuota.Int64 {
+		return httpAPI.ErrQuotaExceeded
+	}
+	if monthlyQuota.Valid && monthlyUsed+next > monthlyQuota.Int64 {
+		return httpAPI.ErrQuotaExceeded
+	}
+	return nil
+}
+
+func (s *Storage) GetUsage(ctx context.Context, userID int64) (models.UsageResp, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH agg AS (
+			SELECT model_id,
+			       SUM(prompt_chars)    FILTER (WHERE created_at >= date_trunc('day', NOW())) AS d_pc,
+			       SUM(response_chars)  FILTER (WHERE created_at >= date_trunc('day', NOW())) AS d_rc,
+			       SUM(prompt_tokens)   FILTER (WHERE created_at >= date_trunc('day', NOW())) AS d_pt,
+			       SUM(response_tokens) FILTER (WHERE created_at >= date_trunc('day', NOW())) AS d_rt,
+			       SUM(prompt_chars)    AS m_pc,
+			       SUM(response_chars)  AS m_rc,
+			       SUM(prompt_tokens)   AS m_pt,
+			       SUM(response_tokens) AS m_rt
+			FROM usage
+			WHERE user_id = $1 AND created_at >= date_trunc('month', NOW())
+			GROUP BY model_id
+		)
+		SELECT b.id, b.name, b.version, b.daily_token_quota, b.monthly_token_quota,
+		       COALESCE(a.d_pc, 0), COALESCE(a.d_rc, 0), COALESCE(a.d_pt, 0), COALESCE(a.d_rt, 0),
+		       COALESCE(a.m_pc, 0), COALESCE(a.m_rc, 0), COALESCE(a.m_pt, 0), COALESCE(a.m_rt, 0)
+		FROM agg a
+		JOIN bot_models b ON b.id = a.model_id
+		ORDER BY b.name, b.version
+	`, userID)
+	if err != nil {
+		return models.UsageResp{}, err
+	}
+	defer rows.Close()
+
+	resp := models.UsageResp{UserID: userID, Items: make([]models.UsageItem, 0, 4)}
+	for rows.Next() {
+		var it models.UsageItem
+		var dailyQuota, monthlyQuota sql.NullInt64
+		if err := rows.Scan(
+			&it.ModelID, &it.ModelName, &it.ModelVersion, &dailyQuota, &monthlyQuota,
+			&it.Daily.PromptChars, &it.Daily.ResponseChars, &it.Daily.PromptTokens, &it.Daily.ResponseTokens,
+			&it.Monthly.PromptChars, &it.Monthly.ResponseChars, &it.Monthly.PromptTokens, &it.Monthly.ResponseTokens,
+		); err != nil {
+			return models.UsageResp{}, err
+		}
+		it.Daily.TotalTokens = it.Daily.PromptTokens + it.Daily.ResponseTokens
+		it.Monthly.TotalTokens = it.Monthly.PromptTokens + it.Monthly.ResponseTokens
+		if dailyQuota.Valid {
+			it.DailyQuota = &dailyQuota.Int64
+		}
+		if monthlyQuota.Valid {
+			it.MonthlyQuota = &monthlyQuota.Int64
+		}
+		resp.Items = append(resp.Items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return models.UsageResp{}, err
+	}
+
+	return resp, nil
+}
+
+// --- validation ---
+
+func (s *Storage) ModelNameExists(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM bot_models
+			WHERE name = $1 AND is_active = TRUE
+		)
+	`, name).Scan(&exists)
+	return exists, err
+}
+
+// --- search ---
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	snippetsPerChat    = 3
+
+	// ts_headline ставит эти маркеры, а в Go мы экранируем текст и меняем их на <mark>
+	hlStart = "\x02"
+	hlStop  = "\x03"
+)
+
+var hlReplacer = strings.NewReplacer(hlStart, "<mark>", hlStop, "</mark>")
+
+func highlight(s string) string {
+	return hlReplacer.Replace(html.EscapeString(s))
+}
+
+// Search ищет по заголовкам и сообщениям неудалённых чатов пользователя
+func (s *Storage) Search(ctx context.Context, userID int64, query string, limit int) (models.SearchResp, error) {
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	opts := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=30, MinWords=10, MaxFragments=2", hlStart, hlStop)
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH q AS (
+			SELECT websearch_to_tsquery('simple', $2) AS query
+		),
+		hits AS (
+			SELECT m.chat_uuid, m.message_uuid, m.role, m.content, m.created_at,
+			       ROW_NUMBER() OVER (
+			           PARTITION BY m.chat_uuid
+			           ORDER BY ts_rank(m.content_tsv, q.query) DESC, m.created_at DESC
+			       ) AS rn,
+			       ts_rank(m.content_tsv, q.query) AS rank
+			FROM messages m
+			JOIN chats c ON c.chat_uuid = m.chat_uuid
+			CROSS JOIN q
+			WHERE c.chat_uuid IN (SELECT chat_uuid FROM chat_members WHERE user_id = $1)
+			  AND c.is_deleted = FALSE
+			  AND m.is_deleted = FALSE
+			  AND m.content_tsv @@ q.query
+		),
+		matched AS (
+			SELECT c.chat_uuid, c.title, c.updated_at, c.last_message_at,
+			       c.title_tsv @@ q.query AS title_match,
+			       GREATEST(
+			           ts_rank(c.title_tsv, q.query),
+			           COALESCE((SELECT MAX(h.rank) FROM hits h WHERE h.chat_uuid = c.chat_uuid), 0)
+			       ) AS rank
+			FROM chats c
+			CROSS JOIN q
+			WHERE c.chat_uuid IN (SELECT chat_uuid FROM chat_members WHERE user_id = $1)
+			  AND c.is_deleted = FALSE
+			  AND (c.title_tsv @@ q.query OR EXISTS (SELECT 1 FROM hits h WHERE h.chat_uuid = c.chat_uuid))
+			ORDER BY rank DESC, c.last_message_at DESC
+			LIMIT $3
+		)
+		SELECT mt.chat_uuid, mt.title, mt.title_match,
+		       CASE WHEN mt.title_match THEN ts_headline('simple', mt.title, q.query, $4) ELSE '' END,
+		       mt.updated_at,
+		       h.message_uuid, h.role,
+		       CASE WHEN h.message_uuid IS NULL THEN NULL ELSE ts_headline('simple', h.content, q.query, $4) END,
+		       h.created_at
+		FROM matched mt
+		CROSS JOIN q
+		LEFT JOIN hits h ON h.chat_uuid = mt.chat_uuid AND h.rn <= $5
+		ORDER BY mt.rank DESC, mt.last_message_at DESC, mt.chat_uuid, h.rn
+	`, userID, query, limit, opts, snippetsPerChat)
+	if err != nil {
+		return models.SearchResp{}, err
+	}
+	defer rows.Close()
+
+	resp := models.SearchResp{Query: query, Items: make([]models.SearchItem, 0, limit)}
+	for rows.Next() {
+		var (
+			chatID, title, titleHL string
+			titleMatch             bool
+			updated                time.Time
+			msgID, role, snippet   sql.NullString
+			created                sql.NullTime
+		)
+		if err := rows.Scan(&chatID, &title, &titleMatch, &titleHL, &updated, &msgID, &role, &snippet, &created); err != nil {
+			return models.SearchResp{}, err
+		}
+
+		// строки идут сгруппированными по чату
+		if n := len(resp.Items); n == 0 || resp.Items[n-1].ChatID != chatID {
+			it := models.SearchItem{
+				ChatID:    chatID,
+				Title:     title,
+				UpdatedAt: updated.UTC().Format(time.RFC3339),
+				Snippets:  make([]models.SearchSnippet, 0, snippetsPerChat),
+			}
+			if titleMatch {
+				it.TitleHighlight = highlight(titleHL)
+			}
+			resp.Items = append(resp.Items, it)
+		}
+
+		if msgID.Valid {
+			last := &resp.Items[len(resp.Items)-1]
+			last.Snippets = append(last.Snippets, models.SearchSnippet{
+				MessageID: msgID.String,
+				Role:      role.String,
+				Snippet:   highlight(snippet.String),
+				CreatedAt: created.Time.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.SearchResp{}, err
+	}
+
+	return resp, nil
+}
+
+// --- purge ---
+
+// purgeableMessages — сообщения, удалённые раньше cutoff, либо из удалённых раньше cutoff чатов
+const purgeableMessages = `
+	SELECT m.message_uuid
+	FROM messages m
+	JOIN chats c ON c.chat_uuid = m.chat_uuid
+	WHERE (m.is_deleted = TRUE AND m.deleted_at < $1)
+	   OR (c.is_deleted = TRUE AND c.deleted_at < $1)
+`
+
+// PurgeDeleted физически удаляет soft-deleted строки старше cutoff.
+// Порядок по FK: feedbacks -> ссылки reply_to -> messages -> chats.
+// Каждый батч — отдельная транзакция, чтобы не держать долгие блокировки.
+func (s *Storage) PurgeDeleted(ctx context.Context, cutoff time.Time, batchSize int, dryRun bool) (models.PurgeReport, error) {
+	const op = "storage.postgres.PurgeDeleted"
+
+	report := models.PurgeReport{Cutoff: cutoff.UTC().Format(time.RFC3339), DryRun: dryRun}
+
+	if dryRun {
+		err := s.db.QueryRowContext(ctx, `
+			WITH pm AS (`+purgeableMessages+`)
+			SELECT
+				(SELECT COUNT(*) FROM pm),
+				(SELECT COUNT(*) FROM message_feedbacks f JOIN pm ON pm.message_uuid = f.message_uuid),
+				(SELECT COUNT(*) FROM chats WHERE is_deleted = TRUE AND deleted_at < $1)
+		`, cutoff).Scan(&report.Messages, &report.Feedbacks, &report.Chats)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+		return report, nil
+	}
+
+	// 1) messages (+ feedbacks)
+	for {
+		msgs, fbs, err := s.purgeMessagesBatch(ctx, cutoff, batchSize)
+		if err != nil {
+			return report, fmt.Errorf("%s: messages: %w", op, err)
+		}
+		report.Messages += msgs
+		report.Feedbacks += fbs
+		if msgs < int64(batchSize) {
+			break
+		}
+	}
+
+	// 2) chats, у которых не осталось сообщений
+	for {
+		res, err := s.db.ExecContext(ctx, `
+			DELETE FROM chats
+			WHERE chat_uuid IN (
+				SELECT c.chat_uuid
+				FROM chats c
+				WHERE c.is_deleted = TRUE AND c.deleted_at < $1
+				  AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_uuid = c.chat_uuid)
+				LIMIT $2
+			)
+		`, cutoff, batchSize)
+		if err != nil {
+			return report, fmt.Errorf("%s: chats: %w", op, err)
+		}
+		aff, _ := res.RowsAffected()
+		report.Chats += aff
+		if aff < int64(batchSize) {
+			break
+		}
+	}
+
+	return report, nil
+}
+
+func (s *Storage) purgeMessagesBatch(ctx context.Context, cutoff time.Time, batchSize int) (messages, feedbacks int64, err error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE purge_batch (message_uuid UUID PRIMARY KEY) ON COMMIT DROP
+	`); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO purge_batch (message_uuid)
+		`+purgeableMessages+`
+		LIMIT $2
+	`, cutoff, batchSize); err != nil {
+		return 0, 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM message_feedbacks
+		WHERE message_uuid IN (SELECT message_uuid FROM purge_batch)
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	feedbacks, _ = res.RowsAffected()
+
+	// reply_to_message_id может указывать на удаляемое сообщение (в т.ч. из живых строк)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE messages
+		SET reply_to_message_id = NULL
+		WHERE reply_to_message_id IN (SELECT message_uuid FROM purge_batch)
+	`); err != nil {
+		return 0, 0, err
+	}
+
+	res, err = tx.ExecContext(ctx, `
+		DELETE FROM messages
+		WHERE message_uuid IN (SELECT message_uuid FROM purge_batch)
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	messages, _ = res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return messages, feedbacks, nil
+}
+
+// --- user data (GDPR) ---
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
+
+// UserData собирает все строки пользователя, включая soft-deleted
+func (s *Storage) UserData(ctx context.Context, userID int64) (models.UserData, error) {
+	const op = "storage.postgres.UserData"
+
+	data := models.UserData{
+		UserID:      userID,
+		Chats:       make([]models.UserDataChat, 0),
+		Messages:    make([]models.UserDataMessage, 0),
+		Memberships: make([]models.UserDataMembership, 0),
+		Feedbacks:   make([]models.UserDataFeedback, 0),
+		Usage:       make([]models.UserDataUsage, 0),
+		Folders:     make([]models.UserDataLabel, 0),
+		Tags:        make([]models.UserDataLabel, 0),
+		Shares:      make([]models.UserDataShare, 0),
+		Templates:   make([]models.UserDataTemplate, 0),
+		Arena:       make([]models.UserDataArenaPreference, 0),
+	}
+
+	// REPEATABLE READ — все таблицы из одного снимка
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// 1) chats
+	rows, err := tx.QueryContext(ctx, `
+		SELECT chat_uuid, title, model_id, settings, is_deleted, created_at, updated_at, deleted_at
+		FROM chats
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("%s: chats: %w", op, err)
+	}
+	for rows.Next() {
+		var c models.UserDataChat
+		var settings []byte
+		var created, updated time.Time
+		var deleted sql.NullTime
+		if err := rows.Scan(&c.ChatUUID, &c.Title, &c.ModelID, &settings, &c.IsDeleted, &created, &updated, &deleted); err != nil {
+			rows.Close()
+			return models.UserData{}, fmt.Errorf("%s: chats: %w", op, err)
+		}
+		if err := json.Unmarshal(settings, &c.Settings); err != nil {
+			rows.Close()
+			return models.UserData{}, fmt.Errorf("%s: chats: settings: %w", op, err)
+		}
+		c.CreatedAt = created.UTC().Format(time.RFC3339)
+		c.UpdatedAt = updated.UTC().Format(time.RFC3339)
+		c.DeletedAt = formatNullTime(deleted)
+		data.Chats = append(data.Chats, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.UserData{}, fmt.Errorf("%s: chats: %w", op, err)
+	}
+
+	// 2) messages: все сообщения своих чатов и свои сообщения в чужих (как в EraseUser)
+	rows, err = tx.QueryContext(ctx, `
+		SELECT m.message_uuid, m.chat_uuid, m.role, m.user_id, m.content, m.reply_to_message_id,
+		       m.is_deleted, m.created_at, m.deleted_at
+		FROM messages m
+		JOIN chats c ON c.chat_uuid = m.chat_uuid
+		WHERE c.user_id = $1 OR m.user_id = $1
+		ORDER BY m.created_at
+	`, userID)
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("%s: messages: %w", op, err)
+	}
+	for rows.Next() {
+		var m models.UserDataMessage
+		var reply sql.NullString
+		var author sql.NullInt64
+		var created time.Time
+		var deleted sql.NullTime
+		if err := rows.Scan(&m.MessageUUID, &m.ChatUUID, &m.Role, &author, &m.Content, &reply, &m.IsDeleted, &created, &deleted); err != nil {
+			rows.Close()
+			return models.UserData{}, fmt.Errorf("%s: messages: %w", op, err)
+		}
+		m.UserID = author.Int64
+		m.ReplyToMessageID = reply.String
+		m.CreatedAt = created.UTC().Format(time.RFC3339)
+		m.DeletedAt = formatNullTime(deleted)
+		data.Messages = append(data.Messages, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.UserData{}, fmt.Errorf("%s: messages: %w", op, err)
+	}
+
+	// 3) memberships
+	rows, err = tx.QueryContext(ctx, `
+		SELECT chat_uuid, role, invited_by, is_pinned, is_archived, created_at
+		FROM chat_members
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("%s: memberships: %w", op, err)
+	}
+	for rows.Next() {
+		var m models.UserDataMembership
+		var invitedBy sql.NullInt64
+		var created time.Time
+		if err := rows.Scan(&m.ChatUUID, &m.Role, &invitedBy, &m.Pinned, &m.Archived, &created); err != nil {
+			rows.Close()
+			return models.UserData{}, fmt.Errorf("%s: memberships: %w", op, err)
+		}
+		m.InvitedBy = invitedBy.Int64
+		m.CreatedAt = created.UTC().Format(time.RFC3339)
+		data.Memberships = append(data.Memberships, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.UserData{}, fmt.Errorf("%s: memberships: %w", op, err)
+	}
+
+	// 4) feedbacks
+	rows, err = tx.QueryContext(ctx, `
+		SELECT message_uuid, model_id, is_positive, COALESCE(reason, ''), COALESCE(comment, ''), created_at, updated_at
+		FROM message_feedbacks
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("%s: feedbacks: %w", op, err)
+	}
+	for rows.Next() {
+		var f models.UserDataFeedback
+		var created, updated time.Time
+		if err := rows.Scan(&f.MessageUUID, &f.ModelID, &f.IsPositive, &f.Reason, &f.Comment, &created, &updated); err != nil {
+			rows.Close()
+			return models.UserData{}, fmt.Errorf("%s: feedbacks: %w", op, err)
+		}
+		f.CreatedAt = created.UTC().Format(time.RFC3339)
+		f.UpdatedAt = updated.UTC().Format(time.RFC3339)
+		data.Feedbacks = append(data.Feedbacks, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.UserData{}, fmt.Errorf("%s: feedbacks: %w", op, err)
+	}
+
+	// 5) usage
+	rows, err = tx.QueryContext(ctx, `
+		SELECT chat_uuid, message_uuid, model_id, prompt_chars, response_chars,
+		       prompt_tokens, response_tokens, created_at
+		FROM usage
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return models.UserData{}, fmt.Errorf("%s: usage: %w", op, err)
+	}
+	for rows.Next() {
+		var u models.UserDataUsage
+		var msg sql.NullString
+		var created time.Time
+		if err := rows.Scan(&u.ChatUUID, &msg, &u.ModelID, &u.PromptChars, &u.ResponseChars,
+			&u.PromptTokens, &u.ResponseTokens, &created); err != nil {
+			rows.Close()
+			return models.UserData{}, fmt.Errorf("%s: usage: %w", op, err)
+		}
+		u.MessageUUID = msg.String
+		u.CreatedAt = created.UTC().Format(time.RFC3339)
+		data.Usage = append(data.Usage, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.UserData{}, fmt.Errorf("%s: usage: %w", op, err)
+	}
+
+	// 6) разметка, ссылки, шаблоны, выборы в arena
+	if err := userDataExtras(ctx, tx, userID, &data); err != nil {
+		return models.UserData{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return data, nil
+}
+
+// anonymousUserID — владелец анонимизированных строк
+const anonymousUserID = 0
+
+const erasedText = "[erased]"
+
+// EraseUser удаляет (anonymize=false) или необратимо обезличивает все строки
+// пользователя в chats, messages, message_feedbacks и usage.
+// Общие чаты с оставшимися участниками передаются им; чужие сообщения не затираются.
+func (s *Storage) EraseUser(ctx context.Context, userID int64, anonymize bool) (models.EraseReport, error) {
+	const op = "storage.postgres.EraseUser"
+
+	report := models.EraseReport{UserID: userID, Mode: "delete"}
+	if anonymize {
+		report.Mode = "anonymize"
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	exec := func(query string, args ...any) (int64, error) {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	// общие чаты, где остались участники, не удаляем: владение переходит
+	// самому давнему editor (если editor'ов нет — viewer'у)
+	if report.TransferredChats, err = exec(`
+		WITH heir AS (
+			SELECT DISTINCT ON (cm.chat_uuid) cm.chat_uuid, cm.user_id
+			FROM chat_members cm
+			JOIN chats c ON c.chat_uuid = cm.chat_uuid
+			WHERE c.user_id = $1 AND cm.user_id <> $1
+			ORDER BY cm.chat_uuid, (cm.role = 'editor') DESC, cm.created_at, cm.user_id
+		), promoted AS (
+			UPDATE chat_members cm
+			SET role = 'owner'
+			FROM heir h
+			WHERE cm.chat_uuid = h.chat_uuid AND cm.user_id = h.user_id
+			RETURNING cm.chat_uuid, cm.user_id
+		)
+		UPDATE chats c
+		SET user_id = p.user_id
+		FROM promoted p
+		WHERE c.chat_uuid = p.chat_uuid
+	`, userID); err != nil {
+		return report, fmt.Errorf("%s: transfer: %w", op, err)
+	}
+	// публичные ссылки, созданные пользователем, больше не действуют
+	if _, err := exec(`DELETE FROM chat_shares WHERE user_id = $1`, userID); err != nil {
+		return report, fmt.Errorf("%s: shares: %w", op, err)
+	}
+
+	// сообщения пользователя в чужих общих чатах (включая переданные выше):
+	// ветка остаётся, текст и автор — нет
+	author := sql.NullInt64{Int64: anonymousUserID, Valid: anonymize}
+	n, err := exec(`
+		UPDATE messages
+		SET content = $2, user_id = $3
+		WHERE user_id = $1
+		  AND chat_uuid NOT IN (SELECT chat_uuid FROM chats WHERE user_id = $1)
+	`, userID, erasedText, author)
+	if err != nil {
+		return report, fmt.Errorf("%s: shared messages: %w", op, err)
+	}
+	if _, err := exec(`
+		DELETE FROM chat_members
+		WHERE user_id = $1
+		  AND chat_uuid NOT IN (SELECT chat_uuid FROM chats WHERE user_id = $1)
+	`, userID); err != nil {
+		return report, fmt.Errorf("%s: memberships: %w", op, err)
+	}
+
+	// папки и теги — личная разметка, для аналитики не нужна
+	if _, err := exec(`DELETE FROM folders WHERE user_id = $1`, userID); err != nil {
+		return report, fmt.Errorf("%s: folders: %w", op, err)
+	}
+	if _, err := exec(`DELETE FROM tags WHERE user_id = $1`, userID); err != nil {
+		return report, fmt.Errorf("%s: tags: %w", op, err)
+	}
+	// шаблоны удаляем и общие: текст авторский
+	if _, err := exec(`DELETE FROM prompt_templates WHERE user_id = $1`, userID); err != nil {
+		return report, fmt.Errorf("%s: templates: %w", op, err)
+	}
+
+	if anonymize {
+		// текст затирается, а структура (модели, оценки, объёмы) остаётся для аналитики.
+		// Сообщения других авторов (бывших участников) не трогаем.
+		if report.Messages, err = exec(`
+			UPDATE messages
+			SET content = $2,
+			    user_id = CASE WHEN user_id = $1 THEN $3 ELSE user_id END
+			WHERE chat_uuid IN (SELECT chat_uuid FROM chats WHERE user_id = $1)
+			  AND (user_id = $1 OR role = 'bot')
+		`, userID, erasedText, anonymousUserID); err != nil {
+			return report, fmt.Errorf("%s: messages: %w", op, err)
+		}
+		if report.Feedbacks, err = exec(`
+			UPDATE message_feedbacks SET user_id = $2, comment = NULL WHERE user_id = $1
+		`, userID, anonymousUserID); err != nil {
+			return report, fmt.Errorf("%s: feedbacks: %w", op, err)
+		}
+		if _, err = exec(`
+			UPDATE arena_preferences SET user_id = $2 WHERE user_id = $1
+		`, userID, anonymousUserID); err != nil {
+			return report, fmt.Errorf("%s: arena: %w", op, err)
+		}
+		if report.Usage, err = exec(`
+			UPDATE usage SET user_id = $2 WHERE user_id = $1
+		`, userID, anonymousUserID); err != nil {
+			return report, fmt.Errorf("%s: usage: %w", op, err)
+		}
+		// публичные ссылки больше не нужны
+		if _, err = exec(`
+			DELETE FROM chat_shares
+			WHERE chat_uuid IN (SELECT chat_uuid FROM chats WHERE user_id = $1)
+		`, userID); err != nil {
+			return report, fmt.Errorf("%s: shares: %w", op, err)
+		}
+		if _, err = exec(`
+			UPDATE chat_members SET user_id = $2 WHERE user_id = $1
+		`, userID, anonymousUserID); err != nil {
+			return report, fmt.Errorf("%s: owners: %w", op, err)
+		}
+		if report.Chats, err = exec(`
+			UPDATE chats
+			SET user_id = $2, title = $3, last_message_preview = NULL, settings = '{}'::jsonb
+			WHERE user_id = $1
+		`, userID, anonymousUserID, erasedText); err != nil {
+			return report, fmt.Errorf("%s: chats: %w", op, err)
+		}
+	} else {
+		// порядок по FK: feedbacks -> messages -> chats; usage.message_uuid обнулится сам
+		if report.Feedbacks, err = exec(`
+			DELETE FROM message_feedbacks
+			WHERE user_id = $1
+			   OR message_uuid IN (
+			       SELECT m.message_uuid
+			       FROM messages m
+			       JOIN chats c ON c.chat_uuid = m.chat_uuid
+			       WHERE c.user_id = $1
+			   )
+		`, userID); err != nil {
+			return report, fmt.Errorf("%s: feedbacks: %w", op, err)
+		}
+		// выборы в своих чатах удалятся каскадом вместе с сообщениями
+		if _, err = exec(`
+			DELETE FROM arena_preferences WHERE user_id = $1
+		`, userID); err != nil {
+			return report, fmt.Errorf("%s: arena: %w", op, err)
+		}
+		// reply_to ссылаются только внутри чата, поэтому один DELETE на все сообщения
+		if report.Messages, err = exec(`
+			DELETE FROM messages
+			WHERE chat_uuid IN (SELECT chat_uuid FROM chats WHERE user_id = $1)
+		`, userID); err != nil {
+			return report, fmt.Errorf("%s: messages: %w", op, err)
+		}
+		if report.Chats, err = exec(`
+			DELETE FROM chats WHERE user_id = $1
+		`, userID); err != nil {
+			return report, fmt.Errorf("%s: chats: %w", op, err)
+		}
+		if report.Usage, err = exec(`
+			DELETE FROM usage WHERE user_id = $1
+		`, userID); err != nil {
+			return report, fmt.Errorf("%s: usage: %w", op, err)
+		}
+	}
+
+	report.Messages += n
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	return report, nil
 }
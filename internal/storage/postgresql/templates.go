@@ -0,0 +1,124 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+	"MicroserviceWebsocket/internal/lib/prompt"
+	httpAPI "MicroserviceWebsocket/internal/server/http"
+)
+
+const templateSelect = `
+	SELECT id, user_id, name, content, is_shared, created_at, updated_at
+	FROM prompt_templates
+`
+
+func scanTemplate(row interface{ Scan(...any) error }) (models.PromptTemplate, error) {
+	var t models.PromptTemplate
+	var created, updated time.Time
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Content, &t.IsShared, &created, &updated); err != nil {
+		return models.PromptTemplate{}, err
+	}
+	t.Variables = prompt.Variables(t.Content)
+	t.CreatedAt = created.UTC().Format(time.RFC3339)
+	t.UpdatedAt = updated.UTC().Format(time.RFC3339)
+	return t, nil
+}
+
+// ListPromptTemplates — свои шаблоны и общие шаблоны других пользователей
+func (s *Storage) ListPromptTemplates(ctx context.Context, userID int64) (models.ListPromptTemplatesResp, error) {
+	const op = "storage.postgres.ListPromptTemplates"
+
+	rows, err := s.db.QueryContext(ctx, templateSelect+`
+		WHERE user_id = $1 OR is_shared
+		ORDER BY user_id <> $1, name, id
+	`, userID)
+	if err != nil {
+		return models.ListPromptTemplatesResp{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	resp := models.ListPromptTemplatesResp{Items: make([]models.PromptTemplate, 0, 16)}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return models.ListPromptTemplatesResp{}, fmt.Errorf("%s: %w", op, err)
+		}
+		resp.Items = append(resp.Items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return models.ListPromptTemplatesResp{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return resp, nil
+}
+
+// GetPromptTemplate — шаблон, видимый пользователю (свой или общий)
+func (s *Storage) GetPromptTemplate(ctx context.Context, userID, templateID int64) (models.PromptTemplate, error) {
+	t, err := scanTemplate(s.db.QueryRowContext(ctx, templateSelect+`
+		WHERE id = $1 AND (user_id = $2 OR is_shared)
+	`, templateID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PromptTemplate{}, httpAPI.ErrTemplateNotFound
+		}
+		return models.PromptTemplate{}, fmt.Errorf("storage.postgres.GetPromptTemplate: %w", err)
+	}
+	return t, nil
+}
+
+func (s *Storage) CreatePromptTemplate(ctx context.Context, req models.PromptTemplateReq) (models.PromptTemplate, error) {
+	shared := req.IsShared != nil && *req.IsShared
+
+	t, err := scanTemplate(s.db.QueryRowContext(ctx, `
+		INSERT INTO prompt_templates (user_id, name, content, is_shared)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING id, user_id, name, content, is_shared, created_at, updated_at
+	`, req.UserID, *req.Name, *req.Content, shared))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PromptTemplate{}, httpAPI.ErrNameTaken
+		}
+		return models.PromptTemplate{}, fmt.Errorf("storage.postgres.CreatePromptTemplate: %w", err)
+	}
+	return t, nil
+}
+
+// UpdatePromptTemplate — менять может только автор; чужой шаблон = не найден
+func (s *Storage) UpdatePromptTemplate(ctx context.Context, templateID int64, req models.PromptTemplateReq) (models.PromptTemplate, error) {
+	t, err := scanTemplate(s.db.QueryRowContext(ctx, `
+		UPDATE prompt_templates
+		SET name      = COALESCE($3, name),
+		    content   = COALESCE($4, content),
+		    is_shared = COALESCE($5, is_shared)
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, name, content, is_shared, created_at, updated_at
+	`, templateID, req.UserID, req.Name, req.Content, req.IsShared))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PromptTemplate{}, httpAPI.ErrTemplateNotFound
+		}
+		if isUniqueViolation(err) {
+			return models.PromptTemplate{}, httpAPI.ErrNameTaken
+		}
+		return models.PromptTemplate{}, fmt.Errorf("storage.postgres.UpdatePromptTemplate: %w", err)
+	}
+	return t, nil
+}
+
+func (s *Storage) DeletePromptTemplate(ctx context.Context, userID, templateID int64) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM prompt_templates WHERE id = $1 AND user_id = $2
+	`, templateID, userID)
+	if err != nil {
+		return fmt.Errorf("storage.postgres.DeletePromptTemplate: %w", err)
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return httpAPI.ErrTemplateNotFound
+	}
+	return nil
+}
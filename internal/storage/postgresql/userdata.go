@@ -0,0 +1,127 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	models "MicroserviceWebsocket/internal/domain"
+)
+
+// collect выполняет запрос и собирает строки через scan (rows закрываются здесь)
+func collect[T any](ctx context.Context, tx *sql.Tx, query string, args []any, scan func(*sql.Rows) (T, error)) ([]T, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]T, 0)
+	for rows.Next() {
+		it, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// userDataLabels — папки или теги пользователя с размеченными чатами
+func userDataLabels(ctx context.Context, tx *sql.Tx, t labelTable, userID int64) ([]models.UserDataLabel, error) {
+	return collect(ctx, tx, fmt.Sprintf(`
+		SELECT l.id, l.name, l.created_at,
+		       COALESCE((SELECT json_agg(x.chat_uuid ORDER BY x.chat_uuid) FROM %s x WHERE x.%s = l.id), '[]')
+		FROM %s l
+		WHERE l.user_id = $1
+		ORDER BY l.created_at, l.id
+	`, t.link, t.linkCol, t.table), []any{userID}, func(rows *sql.Rows) (models.UserDataLabel, error) {
+		var l models.UserDataLabel
+		var chats []byte
+		var created time.Time
+		if err := rows.Scan(&l.ID, &l.Name, &created, &chats); err != nil {
+			return l, err
+		}
+		if err := json.Unmarshal(chats, &l.ChatUUIDs); err != nil {
+			return l, err
+		}
+		l.CreatedAt = created.UTC().Format(time.RFC3339)
+		return l, nil
+	})
+}
+
+// userDataExtras дополняет выгрузку строками, которые EraseUser тоже трогает:
+// папки и теги, публичные ссылки, шаблоны промптов, выборы в arena
+func userDataExtras(ctx context.Context, tx *sql.Tx, userID int64, data *models.UserData) error {
+	var err error
+
+	if data.Folders, err = userDataLabels(ctx, tx, folderTable, userID); err != nil {
+		return fmt.Errorf("folders: %w", err)
+	}
+	if data.Tags, err = userDataLabels(ctx, tx, tagTable, userID); err != nil {
+		return fmt.Errorf("tags: %w", err)
+	}
+
+	data.Shares, err = collect(ctx, tx, `
+		SELECT token, chat_uuid, shared_until, created_at, revoked_at
+		FROM chat_shares
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, []any{userID}, func(rows *sql.Rows) (models.UserDataShare, error) {
+		var sh models.UserDataShare
+		var until, created time.Time
+		var revoked sql.NullTime
+		if err := rows.Scan(&sh.Token, &sh.ChatUUID, &until, &created, &revoked); err != nil {
+			return sh, err
+		}
+		sh.SharedUntil = until.UTC().Format(time.RFC3339)
+		sh.CreatedAt = created.UTC().Format(time.RFC3339)
+		sh.RevokedAt = formatNullTime(revoked)
+		return sh, nil
+	})
+	if err != nil {
+		return fmt.Errorf("shares: %w", err)
+	}
+
+	data.Templates, err = collect(ctx, tx, `
+		SELECT id, name, content, is_shared, created_at, updated_at
+		FROM prompt_templates
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, []any{userID}, func(rows *sql.Rows) (models.UserDataTemplate, error) {
+		var t models.UserDataTemplate
+		var created, updated time.Time
+		if err := rows.Scan(&t.ID, &t.Name, &t.Content, &t.IsShared, &created, &updated); err != nil {
+			return t, err
+		}
+		t.CreatedAt = created.UTC().Format(time.RFC3339)
+		t.UpdatedAt = updated.UTC().Format(time.RFC3339)
+		return t, nil
+	})
+	if err != nil {
+		return fmt.Errorf("templates: %w", err)
+	}
+
+	data.Arena, err = collect(ctx, tx, `
+		SELECT prompt_message_uuid, chosen_message_uuid, chosen_model_id, created_at, updated_at
+		FROM arena_preferences
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, []any{userID}, func(rows *sql.Rows) (models.UserDataArenaPreference, error) {
+		var p models.UserDataArenaPreference
+		var created, updated time.Time
+		if err := rows.Scan(&p.PromptMessageUUID, &p.ChosenMessageUUID, &p.ChosenModelID, &created, &updated); err != nil {
+			return p, err
+		}
+		p.CreatedAt = created.UTC().Format(time.RFC3339)
+		p.UpdatedAt = updated.UTC().Format(time.RFC3339)
+		return p, nil
+	})
+	if err != nil {
+		return fmt.Errorf("arena: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS usage;
+
+ALTER TABLE bot_models
+  DROP COLUMN IF EXISTS monthly_token_quota,
+  DROP COLUMN IF EXISTS daily_token_quota;
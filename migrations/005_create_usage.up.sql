@@ -0,0 +1,22 @@
+-- квоты на модель (NULL = без ограничений)
+ALTER TABLE bot_models
+  ADD COLUMN IF NOT EXISTS daily_token_quota   BIGINT,
+  ADD COLUMN IF NOT EXISTS monthly_token_quota BIGINT;
+
+-- usage: одна строка на каждый ответ бота
+CREATE TABLE IF NOT EXISTS usage (
+  id               BIGINT GENERATED BY DEFAULT AS IDENTITY
+                   (START WITH 1 INCREMENT BY 1) PRIMARY KEY,
+  user_id          BIGINT NOT NULL,
+  model_id         BIGINT NOT NULL REFERENCES bot_models(id),
+  chat_uuid        UUID NOT NULL,
+  message_uuid     UUID REFERENCES messages(message_uuid) ON DELETE SET NULL,
+  prompt_chars     INT NOT NULL DEFAULT 0,
+  response_chars   INT NOT NULL DEFAULT 0,
+  prompt_tokens    INT NOT NULL DEFAULT 0,
+  response_tokens  INT NOT NULL DEFAULT 0,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_user_model_created
+  ON usage (user_id, model_id, created_at);
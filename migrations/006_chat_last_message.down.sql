@@ -0,0 +1,5 @@
+ALTER TABLE messages
+  DROP COLUMN IF EXISTS latency_ms;
+
+ALTER TABLE chats
+  DROP COLUMN IF EXISTS last_message_preview;
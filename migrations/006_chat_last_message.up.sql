@@ -0,0 +1,18 @@
+-- превью последнего сообщения для списка чатов
+ALTER TABLE chats
+  ADD COLUMN IF NOT EXISTS last_message_preview TEXT;
+
+-- время генерации ответа бота
+ALTER TABLE messages
+  ADD COLUMN IF NOT EXISTS latency_ms INT;
+
+-- заполняем превью для уже существующих чатов
+UPDATE chats c
+SET last_message_preview = LEFT(m.content, 120)
+FROM (
+  SELECT DISTINCT ON (chat_uuid) chat_uuid, content
+  FROM messages
+  WHERE is_deleted = FALSE
+  ORDER BY chat_uuid, created_at DESC
+) m
+WHERE m.chat_uuid = c.chat_uuid;
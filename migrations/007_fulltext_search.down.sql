@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_chats_title_tsv;
+DROP INDEX IF EXISTS idx_messages_content_tsv;
+
+ALTER TABLE chats
+  DROP COLUMN IF EXISTS title_tsv;
+
+ALTER TABLE messages
+  DROP COLUMN IF EXISTS content_tsv;
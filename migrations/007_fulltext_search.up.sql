@@ -0,0 +1,14 @@
+-- 'simple': без стемминга, одинаково работает для русского и английского
+ALTER TABLE messages
+  ADD COLUMN IF NOT EXISTS content_tsv tsvector
+  GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;
+
+ALTER TABLE chats
+  ADD COLUMN IF NOT EXISTS title_tsv tsvector
+  GENERATED ALWAYS AS (to_tsvector('simple', title)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_messages_content_tsv
+  ON messages USING GIN (content_tsv);
+
+CREATE INDEX IF NOT EXISTS idx_chats_title_tsv
+  ON chats USING GIN (title_tsv);
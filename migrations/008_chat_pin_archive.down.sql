@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_chats_user_pinned_updated;
+
+ALTER TABLE chats
+  DROP COLUMN IF EXISTS is_archived,
+  DROP COLUMN IF EXISTS is_pinned;
@@ -0,0 +1,7 @@
+ALTER TABLE chats
+  ADD COLUMN IF NOT EXISTS is_pinned   BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- ListChats: закреплённые сверху, дальше по updated_at
+CREATE INDEX IF NOT EXISTS idx_chats_user_pinned_updated
+  ON chats (user_id, is_archived, is_pinned DESC, updated_at DESC, chat_uuid DESC);
@@ -0,0 +1,12 @@
+-- сообщения, удалённые вместе с чатом (а не по отдельности), восстанавливаются при restore
+ALTER TABLE messages
+  ADD COLUMN IF NOT EXISTS deleted_with_chat BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- DeleteChat помечал чат и сообщения в одной транзакции => одинаковый deleted_at
+UPDATE messages m
+SET deleted_with_chat = TRUE
+FROM chats c
+WHERE c.chat_uuid = m.chat_uuid
+  AND c.is_deleted = TRUE
+  AND m.is_deleted = TRUE
+  AND m.deleted_at = c.deleted_at;
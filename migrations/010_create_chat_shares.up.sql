@@ -0,0 +1,12 @@
+-- публичные read-only ссылки на чат
+CREATE TABLE IF NOT EXISTS chat_shares (
+  token        TEXT PRIMARY KEY,
+  chat_uuid    UUID NOT NULL REFERENCES chats(chat_uuid) ON DELETE CASCADE,
+  user_id      BIGINT NOT NULL,
+  shared_until TIMESTAMPTZ NOT NULL, -- снимок: видны сообщения до этого момента
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_shares_chat
+  ON chat_shares (chat_uuid);
@@ -0,0 +1,4 @@
+ALTER TABLE messages
+  DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS chat_members;
@@ -0,0 +1,28 @@
+-- участники чата: chats.user_id остаётся владельцем, доступ — по ролям
+CREATE TABLE IF NOT EXISTS chat_members (
+  chat_uuid   UUID NOT NULL REFERENCES chats(chat_uuid) ON DELETE CASCADE,
+  user_id     BIGINT NOT NULL,
+  role        TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+  invited_by  BIGINT,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (chat_uuid, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_members_user
+  ON chat_members (user_id);
+
+INSERT INTO chat_members (chat_uuid, user_id, role, created_at)
+SELECT chat_uuid, user_id, 'owner', created_at
+FROM chats
+ON CONFLICT DO NOTHING;
+
+-- автор user-сообщения (в общем чате пишут несколько человек)
+ALTER TABLE messages
+  ADD COLUMN IF NOT EXISTS user_id BIGINT;
+
+UPDATE messages m
+SET user_id = c.user_id
+FROM chats c
+WHERE c.chat_uuid = m.chat_uuid
+  AND m.role = 'user'
+  AND m.user_id IS NULL;
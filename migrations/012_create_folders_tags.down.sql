@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS chat_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS folder_chats;
+DROP TABLE IF EXISTS folders;
@@ -0,0 +1,52 @@
+-- папки и теги — личные для пользователя (в общем чате у каждого свои)
+CREATE TABLE IF NOT EXISTS folders (
+  id          BIGINT GENERATED BY DEFAULT AS IDENTITY
+              (START WITH 1 INCREMENT BY 1) PRIMARY KEY,
+  user_id     BIGINT NOT NULL,
+  name        TEXT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, name)
+);
+
+-- чат лежит не больше чем в одной папке пользователя
+CREATE TABLE IF NOT EXISTS folder_chats (
+  user_id     BIGINT NOT NULL,
+  chat_uuid   UUID NOT NULL REFERENCES chats(chat_uuid) ON DELETE CASCADE,
+  folder_id   BIGINT NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, chat_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_chats_folder
+  ON folder_chats (folder_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+  id          BIGINT GENERATED BY DEFAULT AS IDENTITY
+              (START WITH 1 INCREMENT BY 1) PRIMARY KEY,
+  user_id     BIGINT NOT NULL,
+  name        TEXT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS chat_tags (
+  tag_id      BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  chat_uuid   UUID NOT NULL REFERENCES chats(chat_uuid) ON DELETE CASCADE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tag_id, chat_uuid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_tags_chat
+  ON chat_tags (chat_uuid);
+
+DROP TRIGGER IF EXISTS trg_folders_updated_at ON folders;
+CREATE TRIGGER trg_folders_updated_at
+BEFORE UPDATE ON folders
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS trg_tags_updated_at ON tags;
+CREATE TRIGGER trg_tags_updated_at
+BEFORE UPDATE ON tags
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_feedback_model_reason;
+
+ALTER TABLE message_feedbacks
+  DROP COLUMN IF EXISTS comment,
+  DROP COLUMN IF EXISTS reason;
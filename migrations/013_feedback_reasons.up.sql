@@ -0,0 +1,8 @@
+-- причина (код из конфига, проверяется в API) и свободный комментарий
+ALTER TABLE message_feedbacks
+  ADD COLUMN IF NOT EXISTS reason  TEXT,
+  ADD COLUMN IF NOT EXISTS comment TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_feedback_model_reason
+  ON message_feedbacks (model_id, reason)
+  WHERE reason IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE bot_models
+  DROP COLUMN IF EXISTS default_params,
+  DROP COLUMN IF EXISTS context_size,
+  DROP COLUMN IF EXISTS endpoint;
@@ -0,0 +1,5 @@
+-- параметры модели, которыми управляет admin API
+ALTER TABLE bot_models
+  ADD COLUMN IF NOT EXISTS endpoint       TEXT,
+  ADD COLUMN IF NOT EXISTS context_size   INT,
+  ADD COLUMN IF NOT EXISTS default_params JSONB NOT NULL DEFAULT '{}'::jsonb;
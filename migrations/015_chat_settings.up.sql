@@ -0,0 +1,3 @@
+-- переопределения параметров генерации для чата; поверх bot_models.default_params
+ALTER TABLE chats
+  ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,21 @@
+-- библиотека шаблонов промптов: личные (видит автор) и общие (видят все, меняет автор)
+CREATE TABLE IF NOT EXISTS prompt_templates (
+  id          BIGINT GENERATED BY DEFAULT AS IDENTITY
+              (START WITH 1 INCREMENT BY 1) PRIMARY KEY,
+  user_id     BIGINT NOT NULL,
+  name        TEXT NOT NULL,
+  content     TEXT NOT NULL,
+  is_shared   BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_prompt_templates_shared
+  ON prompt_templates (name)
+  WHERE is_shared;
+
+DROP TRIGGER IF EXISTS trg_prompt_templates_updated_at ON prompt_templates;
+CREATE TRIGGER trg_prompt_templates_updated_at
+BEFORE UPDATE ON prompt_templates
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,25 @@
+-- arena: один промпт -> ответы нескольких моделей (bot-сообщения с общим reply_to);
+-- пользователь выбирает лучший ответ
+CREATE TABLE IF NOT EXISTS arena_preferences (
+  id                  BIGINT GENERATED BY DEFAULT AS IDENTITY
+                      (START WITH 1 INCREMENT BY 1) PRIMARY KEY,
+  prompt_message_uuid UUID NOT NULL REFERENCES messages(message_uuid) ON DELETE CASCADE,
+  chosen_message_uuid UUID NOT NULL REFERENCES messages(message_uuid) ON DELETE CASCADE,
+  chosen_model_id     BIGINT NOT NULL REFERENCES bot_models(id),
+  user_id             BIGINT NOT NULL,
+  created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- один выбор на промпт у пользователя; анонимизированные (user_id = 0) не ограничиваем
+CREATE UNIQUE INDEX IF NOT EXISTS uq_arena_preferences_prompt_user
+  ON arena_preferences (prompt_message_uuid, user_id)
+  WHERE user_id <> 0;
+
+CREATE INDEX IF NOT EXISTS idx_arena_preferences_model
+  ON arena_preferences (chosen_model_id, created_at);
+
+DROP TRIGGER IF EXISTS trg_arena_preferences_updated_at ON arena_preferences;
+CREATE TRIGGER trg_arena_preferences_updated_at
+BEFORE UPDATE ON arena_preferences
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1 @@
+-- исправление данных, откатывать нечего
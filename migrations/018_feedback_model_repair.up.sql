@@ -0,0 +1,8 @@
+-- оценки писались с model_id чата; после смены модели и в arena это не та модель,
+-- что ответила. Переносим на messages.model_id.
+UPDATE message_feedbacks f
+SET model_id = m.model_id
+FROM messages m
+WHERE m.message_uuid = f.message_uuid
+  AND m.model_id IS NOT NULL
+  AND f.model_id <> m.model_id;
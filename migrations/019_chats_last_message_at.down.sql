@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_chats_user_pinned_last_message;
+
+CREATE INDEX IF NOT EXISTS idx_chats_user_pinned_updated
+  ON chats (user_id, is_archived, is_pinned DESC, updated_at DESC, chat_uuid DESC);
+
+ALTER TABLE chats DROP COLUMN IF EXISTS last_message_at;
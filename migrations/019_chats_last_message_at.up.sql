@@ -0,0 +1,16 @@
+-- порядок списка чатов: updated_at двигает триггер на любой UPDATE (title, pin,
+-- настройки, модель), из-за чего чат прыгает вверх и ломается keyset-курсор.
+-- last_message_at меняется только при новом сообщении.
+ALTER TABLE chats
+  ADD COLUMN IF NOT EXISTS last_message_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE chats c
+SET last_message_at = COALESCE(
+  (SELECT MAX(m.created_at) FROM messages m WHERE m.chat_uuid = c.chat_uuid),
+  c.created_at
+);
+
+DROP INDEX IF EXISTS idx_chats_user_pinned_updated;
+
+CREATE INDEX IF NOT EXISTS idx_chats_user_pinned_last_message
+  ON chats (user_id, is_archived, is_pinned DESC, last_message_at DESC, chat_uuid DESC);
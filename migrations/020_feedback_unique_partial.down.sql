@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS uq_message_feedbacks_user_message;
+
+-- анонимные дубли не влезут в полный UNIQUE — оставляем по одной оценке
+DELETE FROM message_feedbacks f
+USING message_feedbacks d
+WHERE f.user_id = 0 AND d.user_id = 0
+  AND f.message_uuid = d.message_uuid
+  AND f.id > d.id;
+
+ALTER TABLE message_feedbacks
+  ADD CONSTRAINT message_feedbacks_user_id_message_uuid_key UNIQUE (user_id, message_uuid);
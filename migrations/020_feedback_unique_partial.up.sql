@@ -0,0 +1,9 @@
+-- анонимизация (EraseUser) переписывает user_id на 0; после user-041 один ответ
+-- могут оценить несколько участников, и второй стёртый упирался в UNIQUE.
+-- Как у arena_preferences: уникальность только для реальных пользователей.
+ALTER TABLE message_feedbacks
+  DROP CONSTRAINT IF EXISTS message_feedbacks_user_id_message_uuid_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_message_feedbacks_user_message
+  ON message_feedbacks (user_id, message_uuid)
+  WHERE user_id <> 0;
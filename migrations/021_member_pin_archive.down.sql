@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_chat_members_user_archived_pinned;
+
+ALTER TABLE chats
+  ADD COLUMN IF NOT EXISTS is_pinned   BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- общим состоянием снова становится состояние владельца
+UPDATE chats c
+SET is_pinned = cm.is_pinned, is_archived = cm.is_archived
+FROM chat_members cm
+WHERE cm.chat_uuid = c.chat_uuid AND cm.role = 'owner';
+
+CREATE INDEX IF NOT EXISTS idx_chats_user_pinned_last_message
+  ON chats (user_id, is_archived, is_pinned DESC, last_message_at DESC, chat_uuid DESC);
+
+ALTER TABLE chat_members
+  DROP COLUMN IF EXISTS is_pinned,
+  DROP COLUMN IF EXISTS is_archived;
@@ -0,0 +1,20 @@
+-- закрепление и архив — личное состояние участника, а не общее для чата:
+-- владелец, убравший общий чат в архив, не должен прятать его у остальных
+ALTER TABLE chat_members
+  ADD COLUMN IF NOT EXISTS is_pinned   BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- переносим как есть: каждый участник видит то же, что и до миграции
+UPDATE chat_members cm
+SET is_pinned = c.is_pinned, is_archived = c.is_archived
+FROM chats c
+WHERE c.chat_uuid = cm.chat_uuid;
+
+-- индекс из 019 удаляется вместе с колонками
+ALTER TABLE chats
+  DROP COLUMN IF EXISTS is_pinned,
+  DROP COLUMN IF EXISTS is_archived;
+
+-- ListChats: чаты участника, закреплённые сверху
+CREATE INDEX IF NOT EXISTS idx_chat_members_user_archived_pinned
+  ON chat_members (user_id, is_archived, is_pinned DESC);